@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// region - caller
+
+// CallerFormat selects how the caller of a logging call is reported in the
+// "caller" field.
+type CallerFormat int
+
+const (
+	CallerOff CallerFormat = iota
+	// CallerShort reports the base name of the file and the line: logging.go:42
+	CallerShort
+	// CallerFull reports the full path of the file and the line
+	CallerFull
+	// CallerFunc reports the fully qualified name of the function
+	CallerFunc
+)
+
+// getCallerFormat returns the caller format configured with LOGGING_CALLER
+// (short, full or func); the caller is not reported by default.
+func getCallerFormat() CallerFormat {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("LOGGING_CALLER"))) {
+	case "short", "true", "1":
+		return CallerShort
+	case "full":
+		return CallerFull
+	case "func":
+		return CallerFunc
+	}
+	return CallerOff
+}
+
+// callerSkip is the number of frames between the caller of a Logger method
+// and the backend function that captures the caller: the Logger method itself
+// and the common log method of the backend.
+const callerSkip = 2
+
+// caller returns the caller skip frames above the function calling caller,
+// formatted according to format.
+func caller(format CallerFormat, skip int) string {
+	if format == CallerOff {
+		return ""
+	}
+	return formatCaller(format, callerPC(skip+1))
+}
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+func formatCaller(format CallerFormat, pc uintptr) string {
+	if format == CallerOff || pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	switch format {
+	case CallerFull:
+		return frame.File + ":" + strconv.Itoa(frame.Line)
+	case CallerFunc:
+		return frame.Function
+	default:
+		return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+}
+
+// withCaller puts the caller in front of the fields of an entry.
+func withCaller(c string, args []interface{}) []interface{} {
+	if c == "" {
+		return args
+	}
+	return append([]interface{}{zerolog.CallerFieldName, c}, args...)
+}
+
+// AddCallerSkip returns a logger that reports callers skip frames further up
+// the stack. Wrappers around a Logger use it to report the caller of the
+// wrapper rather than the wrapper itself.
+func AddCallerSkip(l Logger, skip int) Logger {
+	if s, ok := l.(interface{ withCallerSkip(skip int) Logger }); ok && skip != 0 {
+		return s.withCallerSkip(skip)
+	}
+	return l
+}
+
+// endregion
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// nextLine returns the file:line of the line following its call.
+func nextLine() string {
+	_, _, l, _ := runtime.Caller(1)
+	return "caller_test.go:" + strconv.Itoa(l+1)
+}
+
+func TestCallerText(t *testing.T) {
+	t.Setenv("LOGGING_CALLER", "short")
+	var out string
+	l := GetCustomLogger("caller-text", func(msg string) { out = msg })
+	defer DeleteCustomLogger("caller-text")
+
+	expected := nextLine()
+	l.Info("direct")
+	if !strings.Contains(out, " caller="+expected) {
+		t.Errorf("expected %q in %q", expected, out)
+	}
+	expected = nextLine()
+	l.Warn("warn")
+	if !strings.Contains(out, " caller="+expected) {
+		t.Errorf("expected %q in %q", expected, out)
+	}
+	expected = nextLine()
+	PyroscopeLogger(l).Infof("wrapped %d", 1)
+	if !strings.Contains(out, " caller="+expected) || !strings.Contains(out, "wrapped 1") {
+		t.Errorf("expected %q in %q", expected, out)
+	}
+}
+
+func logThroughHelper(l Logger) {
+	l.Info("helper")
+}
+
+func TestCallerSkipFrameCount(t *testing.T) {
+	var out string
+	l := GetCustomLogger("caller-skip", func(msg string) { out = msg }, WithCaller(4))
+	defer DeleteCustomLogger("caller-skip")
+	expected := nextLine()
+	logThroughHelper(l)
+	if !strings.Contains(out, " caller="+expected) {
+		t.Errorf("expected %q in %q", expected, out)
+	}
+}
+
+func TestCallerSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := GetSlogLogger("caller-slog", slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	defer DeleteSlogLogger("caller-slog")
+	expected := nextLine()
+	l.Info("direct")
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %q in %q", expected, buf.String())
+	}
+}
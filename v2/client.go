@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// region - http client
+
+const defaultClientBodyLimit = 4 << 10
+
+// ClientOption configures RoundTripper.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	allowQuery map[string]bool
+	bodyLimit  int
+}
+
+// ClientAllowQuery logs the values of the named query parameters; the values
+// of all other parameters are redacted.
+func ClientAllowQuery(names ...string) ClientOption {
+	return func(o *clientOptions) {
+		for _, name := range names {
+			o.allowQuery[name] = true
+		}
+	}
+}
+
+// ClientBodyLimit sets the number of bytes of request and response bodies
+// logged at the Trace level.
+func ClientBodyLimit(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.bodyLimit = n
+	}
+}
+
+type clientAttemptsKey struct{}
+
+// WithClientAttempts returns a context in which RoundTripper counts the
+// attempts of a request. A retrier that sends the request again with this
+// context gets the number of earlier attempts logged as http.retries;
+// redirects followed by http.Client are not counted.
+func WithClientAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientAttemptsKey{}, new(atomic.Int64))
+}
+
+// clientRetries returns the number of earlier attempts of r, 0 unless its
+// context comes from WithClientAttempts.
+func clientRetries(r *http.Request) int64 {
+	attempts, ok := r.Context().Value(clientAttemptsKey{}).(*atomic.Int64)
+	if !ok {
+		return 0
+	}
+	if r.Response != nil {
+		return max(attempts.Load()-1, 0)
+	}
+	return attempts.Add(1) - 1
+}
+
+// RoundTripper returns a http.RoundTripper that logs every request sent
+// through next to l: Error for transport errors and 5xx responses, Warn for
+// 4xx responses and Info otherwise. Bodies are logged when Trace is enabled.
+// The request id of the context is sent in the X-Request-ID header. Retries
+// are counted for requests with a context from WithClientAttempts.
+func RoundTripper(next http.RoundTripper, l Logger, opts ...ClientOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	o := clientOptions{allowQuery: make(map[string]bool), bodyLimit: defaultClientBodyLimit}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return &roundTripper{next: next, logger: l, options: o}
+}
+
+type roundTripper struct {
+	next    http.RoundTripper
+	logger  Logger
+	options clientOptions
+}
+
+func (t *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	l := t.logger.WithContext(r.Context())
+	id, _ := RequestIDFromContext(r.Context())
+	if id != "" && r.Header.Get(RequestIDHeader) == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(RequestIDHeader, id)
+	}
+	retries := clientRetries(r)
+	trace := l.IsTraceEnabled()
+	var reqBody []byte
+	var reqTruncated bool
+	if trace {
+		reqBody, reqTruncated = t.requestBody(r)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(r)
+
+	args := []interface{}{
+		"http.method", r.Method,
+		"http.url", t.redactURL(r.URL),
+	}
+	level := zerolog.InfoLevel
+	if resp != nil {
+		args = append(args, "http.status", resp.StatusCode)
+		switch {
+		case resp.StatusCode >= http.StatusInternalServerError:
+			level = zerolog.ErrorLevel
+		case resp.StatusCode >= http.StatusBadRequest:
+			level = zerolog.WarnLevel
+		}
+	}
+	args = append(args, "duration", time.Since(start), "http.retries", retries)
+	if err != nil {
+		args = append(args, "error", err)
+		level = zerolog.ErrorLevel
+	}
+	if trace {
+		if reqBody != nil {
+			args = append(args, "http.request.body", string(reqBody))
+			if reqTruncated {
+				args = append(args, "http.request.body_truncated", true)
+			}
+		}
+		if resp != nil {
+			body, truncated := t.responseBody(resp)
+			args = append(args, "http.response.body", string(body))
+			if truncated {
+				args = append(args, "http.response.body_truncated", true)
+			}
+		}
+	}
+	switch level {
+	case zerolog.ErrorLevel:
+		l.Error("outbound request", args...)
+	case zerolog.WarnLevel:
+		l.Warn("outbound request", args...)
+	default:
+		l.Info("outbound request", args...)
+	}
+	return resp, err
+}
+
+// requestBody returns up to the body limit of the request body, read from a
+// copy when the request can provide one.
+func (t *roundTripper) requestBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody == nil {
+		return nil, false
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	defer body.Close()
+	return readLimited(body, t.options.bodyLimit)
+}
+
+// responseBody reads up to the body limit of the response body and puts the
+// bytes back in front of the rest of the body.
+func (t *roundTripper) responseBody(resp *http.Response) ([]byte, bool) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil, false
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, int64(t.options.bodyLimit)+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+	if len(b) > t.options.bodyLimit {
+		return b[:t.options.bodyLimit], true
+	}
+	return b, false
+}
+
+func readLimited(r io.Reader, limit int) ([]byte, bool) {
+	b, _ := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(b) > limit {
+		return b[:limit], true
+	}
+	return b, false
+}
+
+func (t *roundTripper) redactURL(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(c.User.Username())
+	}
+	if c.RawQuery != "" {
+		q := c.Query()
+		for name, values := range q {
+			if t.options.allowQuery[name] {
+				continue
+			}
+			for i := range values {
+				values[i] = "REDACTED"
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// endregion
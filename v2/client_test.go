@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.Header.Get(RequestIDHeader)))
+	}))
+	defer server.Close()
+
+	var out []string
+	l := GetCustomLogger("client", func(msg string) { out = append(out, msg) }).SetLevel("trace")
+	defer DeleteCustomLogger("client")
+	client := &http.Client{Transport: RoundTripper(nil, l, ClientAllowQuery("page"), ClientBodyLimit(8))}
+	ctx := WithRequestID(context.Background(), "req-1")
+	r, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/users?page=2&token=secret", nil)
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hello req-1" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	want := "/users?page=2&token=REDACTED http.status=200 duration="
+	if len(out) != 1 || !strings.Contains(out[0], "INF [client] outbound request  request_id=req-1 http.method=GET http.url=") || !strings.Contains(out[0], want) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	want = " http.response.body=hello re http.response.body_truncated=true"
+	if !strings.HasSuffix(out[0], want) {
+		t.Fatalf("unexpected output: %q", out[0])
+	}
+}
+
+func TestRoundTripperError(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("client-error", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("client-error")
+	client := &http.Client{Transport: RoundTripper(nil, l)}
+	if _, err := client.Get("http://127.0.0.1:1/"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(out) != 1 || !strings.Contains(out[0], "ERR [client-error] outbound request  http.method=GET http.url=http://127.0.0.1:1/ duration=") ||
+		!strings.Contains(out[0], " error=") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestRoundTripperRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var out []string
+	l := GetCustomLogger("client-retries", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("client-retries")
+	client := &http.Client{Transport: RoundTripper(nil, l)}
+	ctx := WithClientAttempts(context.Background())
+	for attempt := 0; attempt < 2; attempt++ {
+		r, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/old", nil)
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+	}
+	want := []string{"http.retries=0", "http.retries=0", "http.retries=1", "http.retries=1"}
+	if len(out) != len(want) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	for i, w := range want {
+		if !strings.Contains(out[i], w) {
+			t.Fatalf("entry %d: expected %s, got %q", i, w, out[i])
+		}
+	}
+}
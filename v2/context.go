@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+)
+
+// region - context
+
+type debugSessionKey struct{}
+
+// WithDebugSession marks ctx as part of the debug session id. Loggers derived
+// with WithContext from such a context write all entries, whatever their
+// level, and tag them with debug_session=<id>.
+func WithDebugSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, debugSessionKey{}, id)
+}
+
+// DebugSessionFromContext returns the debug session ctx is part of.
+func DebugSessionFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(debugSessionKey{}).(string)
+	return id, ok && id != ""
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the id of the request it belongs
+// to.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by ctx.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// loggerContext holds what WithContext takes from a context: the fields to
+// bind (request id, trace context and debug session), whether the level
+// threshold is lifted for a debug session, and the fingers crossed scope.
+type loggerContext struct {
+	fields []interface{}
+	debug  bool
+	scope  *FingersCrossedScope
+}
+
+func newLoggerContext(ctx context.Context) loggerContext {
+	var lc loggerContext
+	if id, ok := RequestIDFromContext(ctx); ok {
+		lc.fields = append(lc.fields, "request_id", id)
+	}
+	lc.fields = append(lc.fields, traceFields(ctx)...)
+	if id, ok := DebugSessionFromContext(ctx); ok {
+		lc.fields = append(lc.fields, "debug_session", id)
+		lc.debug = true
+	} else {
+		lc.scope = fingersCrossedFromContext(ctx)
+	}
+	return lc
+}
+
+func (lc loggerContext) empty() bool {
+	return len(lc.fields) == 0 && lc.scope == nil
+}
+
+// apply returns the level and the scope of a logger derived with
+// WithContext from a logger with level and scope s.
+func (lc loggerContext) apply(level zerolog.Level, s scoped) (zerolog.Level, scoped) {
+	if lc.debug {
+		return zerolog.TraceLevel, scoped{}
+	}
+	if lc.scope == nil {
+		return level, s
+	}
+	if s.scope == nil {
+		s.threshold = level
+	}
+	s.scope = lc.scope
+	if lc.scope.level < level {
+		level = lc.scope.level
+	}
+	return level, s
+}
+
+// appendFields appends fields to bound without sharing the backing array of
+// bound with the result.
+func appendFields(bound []interface{}, fields []interface{}) []interface{} {
+	return append(bound[:len(bound):len(bound)], fields...)
+}
+
+// endregion
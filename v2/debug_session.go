@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region - debug sessions
+
+// DebugSessionHeader is the request header carrying a debug session token.
+const DebugSessionHeader = "X-Debug-Session"
+
+const maxDebugSessionLength = 64
+
+// SignDebugSession returns a token for the debug session id that is valid
+// until expires. The id may only contain letters, digits, '-' and '_'.
+func SignDebugSession(secret []byte, id string, expires time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("empty secret")
+	}
+	if !validDebugSession(id) {
+		return "", errors.New("invalid debug session id")
+	}
+	payload := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + debugSessionSignature(secret, payload), nil
+}
+
+// VerifyDebugSession checks the signature and the expiry of a token created
+// with SignDebugSession and returns the debug session id.
+func VerifyDebugSession(secret []byte, token string) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("empty secret")
+	}
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", errors.New("malformed debug session token")
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(debugSessionSignature(secret, payload))) {
+		return "", errors.New("invalid debug session signature")
+	}
+	id, expires, _ := strings.Cut(payload, ".")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !validDebugSession(id) {
+		return "", errors.New("malformed debug session token")
+	}
+	if time.Now().Unix() > unix {
+		return "", errors.New("expired debug session token")
+	}
+	return id, nil
+}
+
+// DebugSessionMiddleware marks the context of requests carrying a valid token
+// in the X-Debug-Session header with WithDebugSession. Requests without a
+// valid token pass unchanged. Tokens are not accepted in the query string,
+// where they would end up in access logs.
+func DebugSessionMiddleware(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := r.Header.Get(DebugSessionHeader); token != "" {
+				if id, err := VerifyDebugSession(secret, token); err == nil {
+					r = r.WithContext(WithDebugSession(r.Context(), id))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func debugSessionSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validDebugSession keeps ids short and free of characters that could break
+// the text formats, as they end up in every entry of the session.
+func validDebugSession(id string) bool {
+	if id == "" || len(id) > maxDebugSessionLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// endregion
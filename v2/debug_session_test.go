@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugSessionMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	var out []string
+	l := GetCustomLogger("debug-session", func(msg string) { out = append(out, msg) }).SetLevel("info")
+	defer DeleteCustomLogger("debug-session")
+	handler := DebugSessionMiddleware(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.WithContext(r.Context()).Trace("handling")
+	}))
+
+	token, err := SignDebugSession(secret, "incident-42", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(DebugSessionHeader, token)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if len(out) != 1 || !strings.Contains(out[0], "TRC [debug-session] handling  debug_session=incident-42") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	expired, _ := SignDebugSession(secret, "incident-42", time.Now().Add(-time.Minute))
+	forged, _ := SignDebugSession([]byte("other"), "incident-42", time.Now().Add(time.Minute))
+	for _, token := range []string{expired, forged, "incident-42"} {
+		out = nil
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(DebugSessionHeader, token)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if len(out) != 0 {
+			t.Fatalf("%s: unexpected output: %q", token, out)
+		}
+	}
+
+	out = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?debug_session="+token, nil))
+	if len(out) != 0 {
+		t.Fatalf("expected the query parameter to be ignored: %q", out)
+	}
+}
+
+func TestSignDebugSessionInvalid(t *testing.T) {
+	if _, err := SignDebugSession([]byte("secret"), "a.b", time.Now()); err == nil {
+		t.Fatal("expected an error for an invalid id")
+	}
+	if _, err := SignDebugSession(nil, "abc", time.Now()); err == nil {
+		t.Fatal("expected an error for an empty secret")
+	}
+}
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// region - errors
+
+const maxErrorDepth = 32
+const maxStackDepth = 64
+
+var captureErrorStacks atomic.Bool
+
+// Err logs err under the "error" key. Any error passed in the args of a
+// logging call is logged the same way: its message, its type, the chain of
+// causes (errors.Unwrap and every branch of errors.Join) and the stack trace
+// when the error carries one.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// CaptureErrorStacks makes entries logged at Error level or above capture the
+// current stack for errors that do not carry a stack trace of their own. It is
+// safe to call while logging.
+func CaptureErrorStacks(enabled bool) {
+	captureErrorStacks.Store(enabled)
+}
+
+type errorValue struct {
+	err   error
+	stack stackFrames
+}
+
+func newErrorValue(err error) errorValue {
+	result := errorValue{err: err}
+	for e, depth := err, 0; e != nil && depth < maxErrorDepth; e, depth = errors.Unwrap(e), depth+1 {
+		if stack := errorStack(e); len(stack) > 0 {
+			result.stack = stack
+		}
+	}
+	return result
+}
+
+func (v errorValue) stackTrace() stackFrames {
+	return v.stack
+}
+func (v errorValue) MarshalLogObject(enc ObjectEncoder) {
+	marshalError(enc, v.err, 0)
+	if len(v.stack) > 0 {
+		enc.Add("stack", v.stack)
+	}
+}
+func marshalError(enc ObjectEncoder, err error, depth int) {
+	enc.Add("message", err.Error())
+	enc.Add("type", fmt.Sprintf("%T", err))
+	if causes := errorCauses(err); len(causes) > 0 && depth < maxErrorDepth {
+		enc.Add("causes", errorCauseList{errs: causes, depth: depth + 1})
+	}
+}
+
+type errorCauseList struct {
+	errs  []error
+	depth int
+}
+
+func (l errorCauseList) MarshalLogArray(enc ArrayEncoder) {
+	for _, err := range l.errs {
+		enc.Append(errorCause{err: err, depth: l.depth})
+	}
+}
+
+type errorCause struct {
+	err   error
+	depth int
+}
+
+func (c errorCause) MarshalLogObject(enc ObjectEncoder) {
+	marshalError(enc, c.err, c.depth)
+}
+
+func errorCauses(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var result []error
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				result = append(result, cause)
+			}
+		}
+		return result
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
+
+// errorStack returns the stack trace carried by err, if any, as program
+// counters returned by StackTrace() []uintptr.
+func errorStack(err error) []uintptr {
+	if isNilPointer(err) {
+		return nil
+	}
+	if e, ok := err.(interface{ StackTrace() []uintptr }); ok {
+		return e.StackTrace()
+	}
+	return nil
+}
+
+// attachErrorStacks sets the current stack on top-level errors that have no
+// stack of their own.
+func attachErrorStacks(args []interface{}) {
+	var stack stackFrames
+	for i := 1; i < len(args); i += 2 {
+		v, ok := args[i].(errorValue)
+		if !ok || len(v.stack) > 0 {
+			continue
+		}
+		if stack == nil {
+			stack = callers()
+		}
+		v.stack = stack
+		args[i] = v
+	}
+}
+
+var packagePrefix = reflect.TypeOf(errorValue{}).PkgPath() + "."
+
+// callers returns the current stack without the frames of this package.
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	skip := 0
+	for skip < n-1 {
+		fn := runtime.FuncForPC(pcs[skip] - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), packagePrefix) {
+			break
+		}
+		skip++
+	}
+	return pcs[skip:n]
+}
+
+type stackFrames []uintptr
+
+func (s stackFrames) MarshalLogArray(enc ArrayEncoder) {
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" || frame.File != "" {
+			enc.Append(stackFrame(frame))
+		}
+		if !more {
+			break
+		}
+	}
+}
+
+type stackFrame runtime.Frame
+
+func (f stackFrame) MarshalLogObject(enc ObjectEncoder) {
+	enc.Add("func", f.Function)
+	enc.Add("file", f.File)
+	enc.Add("line", f.Line)
+}
+func (f stackFrame) String() string {
+	return f.Function + " (" + f.File + ":" + strconv.Itoa(f.Line) + ")"
+}
+
+// endregion
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type testStackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func (e *testStackError) Error() string {
+	return e.msg
+}
+func (e *testStackError) StackTrace() []uintptr {
+	return e.stack
+}
+
+func newTestStackError(msg string) error {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(1, pcs)
+	return &testStackError{msg: msg, stack: pcs[:n]}
+}
+
+type nilStackError struct {
+	stack []uintptr
+}
+
+func (e *nilStackError) Error() string {
+	return "nil stack"
+}
+func (e *nilStackError) StackTrace() []uintptr {
+	return e.stack
+}
+
+func TestErrorZerolog(t *testing.T) {
+	var buf bytes.Buffer
+	err := fmt.Errorf("save: %w", errors.Join(errors.New("disk full"), errors.New("quota")))
+	zl := zerolog.New(&buf)
+	zerologFields(zl.Error(), prepareArgs(zerolog.ErrorLevel, isReservedKey, nil, nil, []interface{}{Err(err)})).Msg("failed")
+	expected := `"error":{"message":"save: disk full\nquota","type":"*fmt.wrapError","causes":[{"message":"disk full\nquota","type":"*errors.joinError","causes":[{"message":"disk full","type":"*errors.errorString"},{"message":"quota","type":"*errors.errorString"}]}]}`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestErrorStackText(t *testing.T) {
+	var out string
+	l := GetCustomLogger("error-text", func(msg string) { out = msg })
+	defer DeleteCustomLogger("error-text")
+	l.Error("failed", "err", fmt.Errorf("wrapped: %w", newTestStackError("boom")))
+	lines := strings.Split(out, "\n")
+	if !strings.HasSuffix(lines[0], " err=wrapped: boom err.type=*fmt.wrapError err.causes.0=boom") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "\tat ") || !strings.Contains(lines[1], "newTestStackError") {
+		t.Fatalf("expected stack continuation lines, got %q", out)
+	}
+}
+
+func TestErrorStackTypedNil(t *testing.T) {
+	var out string
+	l := GetCustomLogger("error-nil", func(msg string) { out = msg })
+	defer DeleteCustomLogger("error-nil")
+	l.Error("failed", "err", fmt.Errorf("wrapped: %w", (*nilStackError)(nil)))
+	if !strings.HasSuffix(out, " err=wrapped: nil stack err.type=*fmt.wrapError err.causes.0=nil stack") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestErrorCaptureStack(t *testing.T) {
+	CaptureErrorStacks(true)
+	defer CaptureErrorStacks(false)
+	var out string
+	l := GetCustomLogger("error-capture", func(msg string) { out = msg })
+	defer DeleteCustomLogger("error-capture")
+	l.Warning("warning", "err", errors.New("boom"))
+	if strings.Contains(out, "\n") {
+		t.Fatalf("unexpected stack below Error level: %q", out)
+	}
+	l.Error("error", "err", errors.New("boom"))
+	if !strings.Contains(out, "\n\tat testing.tRunner") {
+		t.Fatalf("expected captured stack, got %q", out)
+	}
+}
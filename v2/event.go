@@ -0,0 +1,223 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// region - wide events
+
+// Event collects the fields of a unit of work, typically a request, and
+// writes them as a single canonical entry when the work ends. The methods of
+// a nil *Event do nothing, so code can use EventFromContext without checking
+// whether an event was started.
+type Event struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	logger  Logger
+	message string
+	start   time.Time
+	keys    map[string]int
+	fields  []interface{}
+	err     error
+	panic   *panicValue
+	failed  bool
+	done    bool
+}
+
+type eventKey struct{}
+
+// NewEvent starts an event that End writes to logger with message, and
+// returns a context carrying it.
+func NewEvent(ctx context.Context, logger Logger, message string) (context.Context, *Event) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	e := &Event{
+		logger:  logger,
+		message: message,
+		start:   time.Now(),
+		keys:    make(map[string]int),
+	}
+	ctx = context.WithValue(ctx, eventKey{}, e)
+	e.ctx = ctx
+	return ctx, e
+}
+
+// EventFromContext returns the event started with NewEvent, or nil.
+func EventFromContext(ctx context.Context) *Event {
+	if ctx == nil {
+		return nil
+	}
+	e, _ := ctx.Value(eventKey{}).(*Event)
+	return e
+}
+
+// Set sets key/value pairs; a key that is set again keeps its position and
+// takes the new value.
+func (e *Event) Set(args ...interface{}) *Event {
+	if e == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		e.set(fmt.Sprint(args[i]), args[i+1])
+	}
+	return e
+}
+
+// Inc adds n to the counter key.
+func (e *Event) Inc(key string, n int64) *Event {
+	if e == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if v, ok := e.get(key).(int64); ok {
+		n += v
+	}
+	e.set(key, n)
+	return e
+}
+
+// AddDuration adds d to the duration key, e.g. the time spent in the database
+// over several queries.
+func (e *Event) AddDuration(key string, d time.Duration) *Event {
+	if e == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if v, ok := e.get(key).(time.Duration); ok {
+		d += v
+	}
+	e.set(key, d)
+	return e
+}
+
+// Error records err as the outcome of the event; the event is written at the
+// Error level.
+func (e *Event) Error(err error) *Event {
+	if e == nil || err == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+	e.failed = true
+	return e
+}
+
+// Fail marks the event as failed without an error, e.g. for a 5xx response.
+func (e *Event) Fail() *Event {
+	if e == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failed = true
+	return e
+}
+
+// Panic records a recovered panic value as the outcome of the event. Called
+// from the deferred function that recovered, it records the stack of the
+// panic as well.
+func (e *Event) Panic(value interface{}) *Event {
+	if e == nil {
+		return e
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.panic = &panicValue{value: value, stack: panicStack()}
+	e.failed = true
+	return e
+}
+
+// End writes the event with its duration and outcome. Only the first call
+// writes.
+func (e *Event) End() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	if e.done || e.logger == nil {
+		e.mu.Unlock()
+		return
+	}
+	e.done = true
+	args := make([]interface{}, 0, len(e.fields)+8)
+	args = append(args, e.fields...)
+	args = append(args, "duration", time.Since(e.start))
+	level := zerolog.InfoLevel
+	switch {
+	case e.panic != nil:
+		args = append(args, "outcome", "panic", "panic", *e.panic)
+		level = zerolog.ErrorLevel
+	case e.failed:
+		args = append(args, "outcome", "error")
+		if e.err != nil {
+			args = append(args, "error", e.err)
+		}
+		level = zerolog.ErrorLevel
+	default:
+		args = append(args, "outcome", "success")
+	}
+	l := AddCallerSkip(e.logger.WithContext(e.ctx), 1)
+	e.mu.Unlock()
+	if level == zerolog.ErrorLevel {
+		l.Error(e.message, args...)
+	} else {
+		l.Info(e.message, args...)
+	}
+}
+
+func (e *Event) get(key string) interface{} {
+	if i, ok := e.keys[key]; ok {
+		return e.fields[i+1]
+	}
+	return nil
+}
+func (e *Event) set(key string, value interface{}) {
+	if i, ok := e.keys[key]; ok {
+		e.fields[i+1] = value
+		return
+	}
+	e.keys[key] = len(e.fields)
+	e.fields = append(e.fields, key, value)
+}
+
+// EventMiddleware starts an event for every request and writes it to logger
+// when the request ends, with the method, the path, the status and the size
+// of the response. A panic of the handler is recorded and re-raised.
+func EventMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, e := NewEvent(r.Context(), logger, "request")
+			e.Set("http.method", r.Method, "http.path", r.URL.Path)
+			sw := newStatusWriter(w)
+			defer func() {
+				v := recover()
+				if v != nil {
+					e.Panic(v)
+					sw.status = http.StatusInternalServerError
+				}
+				e.Set("http.status", sw.Status(), "http.bytes", sw.bytes)
+				if sw.Status() >= http.StatusInternalServerError {
+					e.Fail()
+				}
+				e.End()
+				if v != nil {
+					panic(v)
+				}
+			}()
+			next.ServeHTTP(sw, r.WithContext(ctx))
+		})
+	}
+}
+
+// endregion
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvent(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("event", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("event")
+	ctx, e := NewEvent(context.Background(), l, "job")
+	EventFromContext(ctx).Set("user", "john", "cache", "miss").Inc("hits", 1).Inc("hits", 2)
+	EventFromContext(ctx).AddDuration("db", time.Second).AddDuration("db", time.Second).Set("cache", "hit")
+	EventFromContext(context.Background()).Set("ignored", true)
+	e.Error(errors.New("boom"))
+	e.End()
+	e.End()
+	if len(out) != 1 || !strings.Contains(out[0], "ERR [event] job  user=john cache=hit hits=3 db=2s duration=") ||
+		!strings.Contains(out[0], " outcome=error error=boom") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestEventMiddleware(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("event-middleware", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("event-middleware")
+	handler := EventMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		EventFromContext(r.Context()).Set("user", "john")
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if len(out) != 1 || !strings.Contains(out[0], "INF [event-middleware] request  http.method=GET http.path=/ok user=john http.status=200 http.bytes=5 duration=") ||
+		!strings.HasSuffix(out[0], " outcome=success") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	out = nil
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to be re-raised")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+	if len(out) != 1 || !strings.Contains(out[0], "ERR [event-middleware] request  http.method=GET http.path=/panic user=john http.status=500") ||
+		!strings.Contains(out[0], " outcome=panic panic=boom panic.type=string") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"os"
+	"sync"
+)
+
+// region - fatal & panic
+
+var exitFunc = os.Exit
+var panicFunc = func(value interface{}) {
+	panic(value)
+}
+
+var exitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// SetExitFunc replaces os.Exit as the function Fatal ends with, e.g. to test
+// fatal paths without terminating the test binary.
+func SetExitFunc(fn func(code int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
+}
+
+// SetPanicFunc replaces the builtin panic as the function Panic ends with.
+func SetPanicFunc(fn func(value interface{})) {
+	if fn == nil {
+		fn = func(value interface{}) {
+			panic(value)
+		}
+	}
+	panicFunc = fn
+}
+
+// RegisterExitHook registers fn to run before the process is terminated by
+// Fatal or by Recover with PanicExit. Hooks run in registration order.
+func RegisterExitHook(fn func()) {
+	if fn == nil {
+		return
+	}
+	exitHooks.mu.Lock()
+	exitHooks.hooks = append(exitHooks.hooks, fn)
+	exitHooks.mu.Unlock()
+}
+
+func exit(code int) {
+	exitHooks.mu.Lock()
+	hooks := make([]func(), len(exitHooks.hooks))
+	copy(hooks, exitHooks.hooks)
+	exitHooks.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	Shutdown()
+	exitFunc(code)
+}
+
+// endregion
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// resetExitHooks removes the hooks registered by a test.
+func resetExitHooks() {
+	exitHooks.mu.Lock()
+	exitHooks.hooks = nil
+	exitHooks.mu.Unlock()
+}
+
+func TestFatalSemantics(t *testing.T) {
+	var codes []int
+	var hooks int
+	SetExitFunc(func(code int) { codes = append(codes, code) })
+	defer SetExitFunc(nil)
+	RegisterExitHook(func() { hooks++ })
+	defer resetExitHooks()
+	defer DeleteLogger("fatal-zerolog")
+	defer DeleteCustomLogger("fatal-custom")
+	defer DeleteSlogLogger("fatal-slog")
+
+	file, err := os.CreateTemp(t.TempDir(), "fatal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every exit calls Shutdown, which closes the file loggers, so each
+	// logger is created right before it is used
+	loggers := []func() Logger{
+		func() Logger { return GetFileLogger(file, "fatal-file") },
+		func() Logger { return GetLogger("fatal-zerolog") },
+		func() Logger { return GetCustomLogger("fatal-custom", func(string) {}) },
+		func() Logger { return GetSlogLogger("fatal-slog", slog.NewTextHandler(&bytes.Buffer{}, nil)) },
+		GetNoOpLogger,
+	}
+	for _, l := range loggers {
+		l().Fatal("fatal")
+	}
+	if len(codes) != len(loggers) || hooks != len(loggers) {
+		t.Fatalf("expected %d exits and hook runs, got %v and %d", len(loggers), codes, hooks)
+	}
+	for _, code := range codes {
+		if code != 1 {
+			t.Fatalf("unexpected exit code %d", code)
+		}
+	}
+}
+
+func TestPanicSemantics(t *testing.T) {
+	var values []interface{}
+	SetPanicFunc(func(value interface{}) { values = append(values, value) })
+	defer SetPanicFunc(nil)
+	defer DeleteLogger("panic-zerolog")
+	defer DeleteCustomLogger("panic-custom")
+	defer DeleteSlogLogger("panic-slog")
+
+	loggers := []Logger{
+		GetLogger("panic-zerolog"),
+		GetCustomLogger("panic-custom", func(string) {}),
+		GetSlogLogger("panic-slog", slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		GetNoOpLogger(),
+	}
+	for _, l := range loggers {
+		l.Panic("panic %d", "k", "v")
+	}
+	if len(values) != len(loggers) {
+		t.Fatalf("expected %d panics, got %v", len(loggers), values)
+	}
+	for _, value := range values {
+		if value != "panic %d" {
+			t.Fatalf("unexpected panic value %v", value)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"strconv"
+	"strings"
+)
+
+// region - fields
+
+// Field is a key/value pair that takes a single slot in the args of a logging
+// call, e.g. l.Info("done", logging.Group("http", "status", 200), "took", d).
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Group nests the given key/value pairs under name: {"http":{"method":..}} in
+// JSON and http.method=.. in the text formats.
+func Group(name string, args ...interface{}) Field {
+	return Field{Key: name, Value: group(args)}
+}
+
+type group []interface{}
+
+func (g group) MarshalLogObject(enc ObjectEncoder) {
+	for i := 0; i+1 < len(g); i += 2 {
+		enc.Add(fmt.Sprint(g[i]), g[i+1])
+	}
+}
+
+func appendGroup(groups []string, name string) []string {
+	result := make([]string, len(groups), len(groups)+1)
+	copy(result, groups)
+	return append(result, name)
+}
+
+// prepareArgs builds the final field list of an entry: the fields bound to the
+// logger followed by the normalized args of the call, nested under the groups
+// of the logger (outermost first), with the key policy applied to the keys
+// reserved by the backend.
+func prepareArgs(level zerolog.Level, reserved func(string) bool, bound []interface{}, groups []string, args []interface{}) []interface{} {
+	args = normalizeArgs(args)
+	if captureErrorStacks.Load() && level >= zerolog.ErrorLevel && level <= zerolog.PanicLevel {
+		attachErrorStacks(args)
+	}
+	if len(args) > 0 {
+		for i := len(groups) - 1; i >= 0; i-- {
+			args = []interface{}{groups[i], group(args)}
+		}
+	}
+	if len(bound) > 0 {
+		b := normalizeArgs(bound)
+		args = append(b[:len(b):len(b)], args...)
+	}
+	return sanitizeKeys(args, reserved)
+}
+
+// normalizeArgs turns args into a flat key/value list: Field values are
+// expanded in place, lazy values are resolved, errors are prepared for
+// structured output and groups are normalized recursively. The caller's slice
+// is never modified; if there is nothing to normalize, args is returned as is.
+func normalizeArgs(args []interface{}) []interface{} {
+	if !needsNormalization(args) {
+		return args
+	}
+	result := make([]interface{}, 0, len(args)+2)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(Field); ok {
+			result = append(result, f.Key, normalizeValue(f.Value))
+			i++
+			continue
+		}
+		if i+1 < len(args) {
+			result = append(result, args[i], normalizeValue(args[i+1]))
+		}
+		i += 2
+	}
+	return result
+}
+func needsNormalization(args []interface{}) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case Field, LazyValue, group, error:
+			return true
+		}
+	}
+	return false
+}
+func normalizeValue(value interface{}) interface{} {
+	if lv, ok := value.(LazyValue); ok {
+		value = lv.Resolve()
+	}
+	switch v := value.(type) {
+	case group:
+		return group(normalizeArgs(v))
+	case ObjectMarshaler:
+		return value
+	case error:
+		if !isNilPointer(v) {
+			return newErrorValue(v)
+		}
+	}
+	return value
+}
+
+// endregion
+
+// region - text fields
+
+// textFields renders key/value pairs the way the file and custom loggers print
+// them: " key=value" for every pair, with nested objects and arrays flattened
+// to " parent.child=value" and " parent.0=value". Stack traces of errors go to
+// indented continuation lines after the fields.
+func textFields(args ...interface{}) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(args); i += 2 {
+		appendTextField(&sb, fmt.Sprint(args[i]), args[i+1])
+	}
+	for i := 1; i < len(args); i += 2 {
+		if v, ok := args[i].(interface{ stackTrace() stackFrames }); ok {
+			v.stackTrace().MarshalLogArray(textStackEncoder{&sb})
+		}
+	}
+	return sb.String()
+}
+
+func appendTextField(sb *strings.Builder, key string, value interface{}) {
+	switch v := value.(type) {
+	case errorValue:
+		appendTextError(sb, key, v.err)
+		return
+	case panicValue:
+		if err, ok := v.value.(error); ok {
+			appendTextError(sb, key, err)
+		} else {
+			appendTextField(sb, key, v.value)
+			appendTextField(sb, key+".type", fmt.Sprintf("%T", v.value))
+		}
+		return
+	case ObjectMarshaler:
+		v.MarshalLogObject(&textObjectEncoder{sb: sb, prefix: key + "."})
+		return
+	case ArrayMarshaler:
+		v.MarshalLogArray(&textArrayEncoder{sb: sb, prefix: key + "."})
+		return
+	}
+	sb.WriteString(" ")
+	sb.WriteString(key)
+	sb.WriteString("=")
+	if s, ok := textValue(value); ok {
+		sb.WriteString(s)
+	} else {
+		fmt.Fprintf(sb, "%v", value)
+	}
+}
+
+// appendTextError writes " key=message key.type=type" followed by the causes
+// of err in depth-first order as " key.causes.N=message".
+func appendTextError(sb *strings.Builder, key string, err error) {
+	appendTextField(sb, key, err.Error())
+	appendTextField(sb, key+".type", fmt.Sprintf("%T", err))
+	index := 0
+	var walk func(err error, depth int)
+	walk = func(err error, depth int) {
+		for _, cause := range errorCauses(err) {
+			appendTextField(sb, key+".causes."+strconv.Itoa(index), cause.Error())
+			index++
+			if depth < maxErrorDepth {
+				walk(cause, depth+1)
+			}
+		}
+	}
+	walk(err, 0)
+}
+
+type textStackEncoder struct {
+	sb *strings.Builder
+}
+
+func (e textStackEncoder) Append(value interface{}) {
+	e.sb.WriteString("\n\tat ")
+	e.sb.WriteString(fmt.Sprint(value))
+}
+
+type textObjectEncoder struct {
+	sb     *strings.Builder
+	prefix string
+}
+
+func (e *textObjectEncoder) Add(key string, value interface{}) {
+	appendTextField(e.sb, e.prefix+key, value)
+}
+
+type textArrayEncoder struct {
+	sb     *strings.Builder
+	prefix string
+	index  int
+}
+
+func (e *textArrayEncoder) Append(value interface{}) {
+	appendTextField(e.sb, e.prefix+strconv.Itoa(e.index), value)
+	e.index++
+}
+
+// endregion
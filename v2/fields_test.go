@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"strings"
+	"testing"
+)
+
+func TestGroupText(t *testing.T) {
+	var out string
+	l := GetCustomLogger("group-text", func(msg string) { out = msg })
+	defer DeleteCustomLogger("group-text")
+	l.Info("request", Group("http", "method", "GET", "status", 200), "took", "5ms")
+	if !strings.HasSuffix(out, " http.method=GET http.status=200 took=5ms") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	l.WithGroup("db").Info("query", "table", "users", Group("stats", "rows", 3))
+	if !strings.HasSuffix(out, " db.table=users db.stats.rows=3") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestGroupZerolog(t *testing.T) {
+	var buf bytes.Buffer
+	zl := zerolog.New(&buf)
+	args := []interface{}{"table", "users", Group("stats", "rows", Lazy(func() interface{} { return 3 }))}
+	zerologFields(zl.Info(), prepareArgs(zerolog.InfoLevel, isReservedKey, nil, []string{"db"}, args)).Msg("query")
+	expected := `"db":{"table":"users","stats":{"rows":3}}`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
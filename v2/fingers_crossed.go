@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"net/http"
+	"sync"
+)
+
+// region - fingers crossed
+
+const (
+	defaultFingersCrossedEntries = 1000
+	defaultFingersCrossedBytes   = 1 << 20
+)
+
+// FingersCrossedScope buffers the entries that loggers derived with
+// WithContext would otherwise drop because of their level. When an entry at
+// or above the trigger level (Error by default) is logged in the scope, the
+// buffered entries are written and the rest of the scope is logged without
+// buffering. Entries still buffered when the scope ends are discarded.
+type FingersCrossedScope struct {
+	mu         sync.Mutex
+	level      zerolog.Level
+	trigger    zerolog.Level
+	maxEntries int
+	maxBytes   int
+	entries    []bufferedEntry
+	bytes      int
+	dropped    int
+	triggered  bool
+	ended      bool
+}
+
+type bufferedEntry struct {
+	size  int
+	write func()
+}
+
+type FingersCrossedOption func(*FingersCrossedScope)
+
+// FingersCrossedLevel sets the lowest level that is buffered; entries below
+// it are dropped as usual. The default is Trace.
+func FingersCrossedLevel(level zerolog.Level) FingersCrossedOption {
+	return func(s *FingersCrossedScope) {
+		s.level = level
+	}
+}
+
+// FingersCrossedTrigger sets the level that flushes the buffer.
+func FingersCrossedTrigger(level zerolog.Level) FingersCrossedOption {
+	return func(s *FingersCrossedScope) {
+		s.trigger = level
+	}
+}
+
+// FingersCrossedMaxEntries bounds the number of buffered entries; the oldest
+// entries are dropped first.
+func FingersCrossedMaxEntries(n int) FingersCrossedOption {
+	return func(s *FingersCrossedScope) {
+		s.maxEntries = n
+	}
+}
+
+// FingersCrossedMaxBytes bounds the estimated memory of the buffered entries;
+// the oldest entries are dropped first.
+func FingersCrossedMaxBytes(n int) FingersCrossedOption {
+	return func(s *FingersCrossedScope) {
+		s.maxBytes = n
+	}
+}
+
+// FingersCrossed starts a scope and returns a context carrying it. The caller
+// has to call End when the scope is over.
+func FingersCrossed(ctx context.Context, opts ...FingersCrossedOption) (context.Context, *FingersCrossedScope) {
+	s := &FingersCrossedScope{
+		level:      zerolog.TraceLevel,
+		trigger:    zerolog.ErrorLevel,
+		maxEntries: defaultFingersCrossedEntries,
+		maxBytes:   defaultFingersCrossedBytes,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, fingersCrossedKey{}, s), s
+}
+
+// FingersCrossedMiddleware runs every request in its own scope.
+func FingersCrossedMiddleware(opts ...FingersCrossedOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, scope := FingersCrossed(r.Context(), opts...)
+			defer scope.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type fingersCrossedKey struct{}
+
+func fingersCrossedFromContext(ctx context.Context) *FingersCrossedScope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(fingersCrossedKey{}).(*FingersCrossedScope)
+	return s
+}
+
+// Flush writes the buffered entries and stops buffering, as if an entry at
+// the trigger level had been logged.
+func (s *FingersCrossedScope) Flush() {
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.bytes = 0
+	s.triggered = true
+	s.mu.Unlock()
+	for _, e := range entries {
+		e.write()
+	}
+}
+
+// End discards the buffered entries. Entries logged after End are no longer
+// buffered.
+func (s *FingersCrossedScope) End() {
+	s.mu.Lock()
+	s.entries = nil
+	s.bytes = 0
+	s.ended = true
+	s.mu.Unlock()
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (s *FingersCrossedScope) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *FingersCrossedScope) add(level zerolog.Level, message string, args []interface{}, write func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.triggered {
+		return false
+	}
+	if s.ended || level < s.level {
+		return true
+	}
+	size := entrySize(message, args)
+	s.entries = append(s.entries, bufferedEntry{size: size, write: write})
+	s.bytes += size
+	for len(s.entries) > 0 && (len(s.entries) > s.maxEntries || s.bytes > s.maxBytes) {
+		s.bytes -= s.entries[0].size
+		s.entries[0] = bufferedEntry{}
+		s.entries = s.entries[1:]
+		s.dropped++
+	}
+	return true
+}
+
+// entrySize estimates the memory an entry holds on to while it is buffered.
+func entrySize(message string, args []interface{}) int {
+	n := len(message)
+	for _, arg := range args {
+		if s, ok := arg.(string); ok {
+			n += len(s)
+		} else {
+			n += 16
+		}
+	}
+	return n
+}
+
+// scoped is embedded by loggers derived with WithContext from a context with
+// a scope; threshold is the level of the logger before it was lowered to the
+// level of the scope.
+type scoped struct {
+	scope     *FingersCrossedScope
+	threshold zerolog.Level
+}
+
+// buffer hands an entry below the threshold to the scope and returns true if
+// the scope took care of it. Entries at or above the trigger level flush the
+// scope first.
+func (s scoped) buffer(level zerolog.Level, message string, args []interface{}, write func()) bool {
+	if s.scope == nil {
+		return false
+	}
+	if level < s.threshold {
+		return s.scope.add(level, message, args, write)
+	}
+	if level >= s.scope.trigger {
+		s.scope.Flush()
+	}
+	return false
+}
+
+// endregion
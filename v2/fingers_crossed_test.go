@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFingersCrossedFlush(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("fingers-flush", func(msg string) { out = append(out, msg) }).SetLevel("info")
+	defer DeleteCustomLogger("fingers-flush")
+	ctx, scope := FingersCrossed(context.Background(), FingersCrossedLevel(zerolog.DebugLevel), FingersCrossedMaxEntries(2))
+	defer scope.End()
+	sl := l.WithContext(ctx)
+	sl.Trace("dropped")
+	sl.Debug("first")
+	sl.Debug("second")
+	sl.Debug("third")
+	sl.Info("written")
+	if len(out) != 1 || !strings.Contains(out[0], "written") {
+		t.Fatalf("expected only the info entry: %q", out)
+	}
+	sl.Error("failed")
+	sl.Debug("after")
+	got := strings.Join(out, "\n")
+	for i, want := range []string{"written", "second", "third", "failed", "after"} {
+		if i >= len(out) || !strings.Contains(out[i], want) {
+			t.Fatalf("expected %q at %d: %s", want, i, got)
+		}
+	}
+	if len(out) != 5 || scope.Dropped() != 1 {
+		t.Fatalf("unexpected output (%d dropped): %s", scope.Dropped(), got)
+	}
+}
+
+func TestFingersCrossedMiddleware(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("fingers-middleware", func(msg string) { out = append(out, msg) }).SetLevel("info")
+	defer DeleteCustomLogger("fingers-middleware")
+	handler := FingersCrossedMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.WithContext(r.Context()).Debug("handling", "path", r.URL.Path)
+		if r.URL.Path == "/fail" {
+			l.WithContext(r.Context()).Error("failed")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if len(out) != 0 {
+		t.Fatalf("expected the debug entry to be discarded: %q", out)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	if len(out) != 2 || !strings.Contains(out[0], "DBG [fingers-middleware] handling  path=/fail") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"strings"
+	"time"
+)
+
+// region - text format
+
+const defaultTimeFormat = "2006-01-02 15:04:05.000"
+const defaultTextFormat = "{time} {level} [{logger}] {message} {fields}"
+const defaultTextFormatWithoutTs = "{level} [{logger}] {message} {fields}"
+
+// textFormat renders the lines of the text backends from a template.
+type textFormat struct {
+	timeFormat string
+	parts      []string
+}
+
+func newTextFormat(o options, timestamp bool) textFormat {
+	template := o.format
+	if template == "" {
+		template = defaultTextFormat
+		if !timestamp {
+			template = defaultTextFormatWithoutTs
+		}
+	}
+	f := textFormat{timeFormat: o.timeFormat}
+	if f.timeFormat == "" {
+		f.timeFormat = defaultTimeFormat
+	}
+	if !timestamp {
+		f.timeFormat = ""
+	}
+	f.parts = parseTextFormat(template)
+	return f
+}
+
+// parseTextFormat splits a template into literal text and placeholders.
+func parseTextFormat(template string) []string {
+	var parts []string
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		switch template[start:end] {
+		case "{time}", "{level}", "{logger}", "{message}", "{fields}":
+			if start > 0 {
+				parts = append(parts, template[:start])
+			}
+			parts = append(parts, template[start:end])
+		default:
+			parts = append(parts, template[:end])
+		}
+		template = template[end:]
+	}
+	if template != "" {
+		parts = append(parts, template)
+	}
+	return parts
+}
+
+func (f textFormat) format(t time.Time, level zerolog.Level, logger, message string, args ...interface{}) string {
+	var sb strings.Builder
+	for _, part := range f.parts {
+		switch part {
+		case "{time}":
+			if f.timeFormat != "" {
+				sb.WriteString(t.Format(f.timeFormat))
+			}
+		case "{level}":
+			_, _ = fmt.Fprintf(&sb, "%3s", logLevelAbbr(level))
+		case "{logger}":
+			sb.WriteString(logger)
+		case "{message}":
+			sb.WriteString(message)
+		case "{fields}":
+			sb.WriteString(textFields(args...))
+		default:
+			sb.WriteString(part)
+		}
+	}
+	return sb.String()
+}
+
+// endregion
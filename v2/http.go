@@ -0,0 +1,266 @@
+package logging
+
+import (
+	"bufio"
+	"github.com/rs/zerolog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// region - http
+
+// RequestIDHeader is the header carrying the id of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// HTTPOption configures HTTPMiddleware.
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	skip            []func(r *http.Request) bool
+	sample          float64
+	requestHeaders  []string
+	responseHeaders []string
+	redact          map[string]bool
+}
+
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key"}
+
+// HTTPSkip skips the requests fn returns true for.
+func HTTPSkip(fn func(r *http.Request) bool) HTTPOption {
+	return func(o *httpOptions) {
+		if fn != nil {
+			o.skip = append(o.skip, fn)
+		}
+	}
+}
+
+// HTTPSkipPaths skips requests for the paths, e.g. health checks.
+func HTTPSkipPaths(paths ...string) HTTPOption {
+	return HTTPSkip(func(r *http.Request) bool {
+		for _, p := range paths {
+			if r.URL.Path == p {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// HTTPSample logs only the given fraction of the requests with a status
+// below 400; failed requests are always logged.
+func HTTPSample(rate float64) HTTPOption {
+	return func(o *httpOptions) {
+		o.sample = rate
+	}
+}
+
+// HTTPRequestHeaders logs the named request headers.
+func HTTPRequestHeaders(names ...string) HTTPOption {
+	return func(o *httpOptions) {
+		o.requestHeaders = append(o.requestHeaders, names...)
+	}
+}
+
+// HTTPResponseHeaders logs the named response headers.
+func HTTPResponseHeaders(names ...string) HTTPOption {
+	return func(o *httpOptions) {
+		o.responseHeaders = append(o.responseHeaders, names...)
+	}
+}
+
+// HTTPRedactHeaders replaces the values of the named headers with
+// "[REDACTED]", in addition to Authorization, Cookie, Proxy-Authorization,
+// Set-Cookie and X-Api-Key.
+func HTTPRedactHeaders(names ...string) HTTPOption {
+	return func(o *httpOptions) {
+		for _, name := range names {
+			o.redact[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// HTTPMiddleware writes an access log entry to l for every request: Error
+// for a 5xx status, Warn for a 4xx status and Info otherwise. A panic of the
+// handler is logged with status 500 and re-raised.
+func HTTPMiddleware(l Logger, opts ...HTTPOption) func(http.Handler) http.Handler {
+	o := httpOptions{sample: 1, redact: make(map[string]bool)}
+	for _, name := range defaultRedactedHeaders {
+		o.redact[name] = true
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, skip := range o.skip {
+				if skip(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			start := time.Now()
+			sw := newStatusWriter(w)
+			defer func() {
+				v := recover()
+				if v != nil {
+					sw.status = http.StatusInternalServerError
+				}
+				o.log(l, r, sw, time.Since(start))
+				if v != nil {
+					panic(v)
+				}
+			}()
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+func (o httpOptions) log(l Logger, r *http.Request, sw *statusWriter, d time.Duration) {
+	status := sw.Status()
+	level := zerolog.InfoLevel
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = zerolog.ErrorLevel
+	case status >= http.StatusBadRequest:
+		level = zerolog.WarnLevel
+	default:
+		if o.sample < 1 && rand.Float64() >= o.sample {
+			return
+		}
+	}
+	args := []interface{}{
+		"http.method", r.Method,
+		"http.path", r.URL.Path,
+	}
+	if r.Pattern != "" {
+		args = append(args, "http.route", r.Pattern)
+	}
+	args = append(args,
+		"http.status", status,
+		"http.bytes", sw.bytes,
+		"duration", d,
+		"http.remote_addr", r.RemoteAddr,
+		"http.user_agent", r.UserAgent(),
+	)
+	// the request id of the context is bound by WithContext
+	if _, ok := RequestIDFromContext(r.Context()); !ok {
+		if id := requestID(r, sw); id != "" {
+			args = append(args, "request_id", id)
+		}
+	}
+	if len(o.requestHeaders) > 0 {
+		args = append(args, "http.request.headers", headerFields{r.Header, o.requestHeaders, o.redact})
+	}
+	if len(o.responseHeaders) > 0 {
+		args = append(args, "http.response.headers", headerFields{sw.Header(), o.responseHeaders, o.redact})
+	}
+	l = l.WithContext(r.Context())
+	switch level {
+	case zerolog.ErrorLevel:
+		l.Error("request", args...)
+	case zerolog.WarnLevel:
+		l.Warn("request", args...)
+	default:
+		l.Info("request", args...)
+	}
+}
+
+func requestID(r *http.Request, w http.ResponseWriter) string {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	return w.Header().Get(RequestIDHeader)
+}
+
+// headerFields logs the named headers of h that are present, with the values
+// of the redacted ones replaced.
+type headerFields struct {
+	h      http.Header
+	names  []string
+	redact map[string]bool
+}
+
+func (f headerFields) MarshalLogObject(enc ObjectEncoder) {
+	for _, name := range f.names {
+		name = http.CanonicalHeaderKey(name)
+		values := f.h.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if f.redact[name] {
+			value = "[REDACTED]"
+		}
+		enc.Add(strings.ToLower(name), value)
+	}
+}
+
+// statusWriter records the status and the size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the first final status. Informational statuses such as
+// 103 Early Hints precede the final one and are not recorded; 101 Switching
+// Protocols ends the response and is.
+func (w *statusWriter) WriteHeader(status int) {
+	informational := status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+	if w.status == 0 && !informational {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers such as WebSocket upgraders take over the connection.
+// A hijacked response without a status is logged as 101 Switching Protocols.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status of the response, 200 if the handler did not set
+// one.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// endregion
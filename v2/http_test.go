@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("http", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("http")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte("john"))
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	handler := HTTPMiddleware(l,
+		HTTPSkipPaths("/healthz"),
+		HTTPRequestHeaders("Authorization", "Accept"),
+		HTTPResponseHeaders("Set-Cookie"),
+	)(mux)
+
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Accept", "text/plain")
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	want := "INF [http] request  http.method=GET http.path=/users/42 http.route=GET /users/{id} http.status=200 http.bytes=4 duration="
+	if len(out) != 1 || !strings.Contains(out[0], want) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	want = " http.remote_addr=192.0.2.1:1234 http.user_agent=test request_id=req-1" +
+		" http.request.headers.authorization=[REDACTED] http.request.headers.accept=text/plain" +
+		" http.response.headers.set-cookie=[REDACTED]"
+	if !strings.HasSuffix(out[0], want) {
+		t.Fatalf("unexpected output: %q", out[0])
+	}
+
+	out = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	if len(out) != 1 || !strings.Contains(out[0], "WRN [http] request  http.method=GET http.path=/missing http.status=404") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestHTTPMiddlewareSample(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("http-sample", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("http-sample")
+	handler := HTTPMiddleware(l, HTTPSample(0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	if len(out) != 1 || !strings.Contains(out[0], "ERR [http-sample] request  http.method=GET http.path=/fail http.status=502") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestHTTPMiddlewareInformationalStatus(t *testing.T) {
+	var out []string
+	l := GetCustomLogger("http-informational", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("http-informational")
+	handler := HTTPMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</app.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/hints", nil))
+	if len(out) != 1 || !strings.Contains(out[0], "ERR [http-informational] request  http.method=GET http.path=/hints http.status=500") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	logged := make(chan string, 1)
+	l := GetCustomLogger("http-hijack", func(msg string) { logged <- msg })
+	defer DeleteCustomLogger("http-hijack")
+	server := httptest.NewServer(HTTPMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "no hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+	})))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n"))
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(status, "HTTP/1.1 101") {
+		t.Fatalf("unexpected status line %q: %v", status, err)
+	}
+	select {
+	case msg := <-logged:
+		if !strings.Contains(msg, "http.path=/ws http.status=101") {
+			t.Fatalf("unexpected output: %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the request log")
+	}
+}
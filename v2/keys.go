@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"log/slog"
+	"sync/atomic"
+)
+
+// region - key policy
+
+// KeyPolicy decides what happens to fields whose key is reserved by the
+// logger itself (logger, level, time, message, caller, and msg and source for
+// slog loggers) or was already used by an earlier field of the same entry or
+// group. Reserved keys always belong to the logger: with every policy except
+// KeyPolicyRename the offending field is dropped.
+type KeyPolicy int
+
+const (
+	// KeyPolicyRename keeps the field under its key prefixed with the key
+	// prefix, "fields." unless changed with SetKeyPrefix.
+	KeyPolicyRename KeyPolicy = iota
+	// KeyPolicyLastWins keeps the last of the duplicate fields.
+	KeyPolicyLastWins
+	// KeyPolicyFirstWins keeps the first of the duplicate fields.
+	KeyPolicyFirstWins
+	// KeyPolicyReject drops the offending field and reports it to the error
+	// handler.
+	KeyPolicyReject
+)
+
+var keyPolicy atomic.Int32
+var keyPrefix atomic.Pointer[string]
+
+// SetKeyPolicy sets the key policy, KeyPolicyRename by default. It is safe to
+// call while logging.
+func SetKeyPolicy(policy KeyPolicy) {
+	keyPolicy.Store(int32(policy))
+}
+
+// SetKeyPrefix sets the prefix KeyPolicyRename puts in front of offending
+// keys. It is safe to call while logging.
+func SetKeyPrefix(prefix string) {
+	if prefix != "" {
+		keyPrefix.Store(&prefix)
+	}
+}
+func currentKeyPrefix() string {
+	if p := keyPrefix.Load(); p != nil {
+		return *p
+	}
+	return "fields."
+}
+
+func isReservedKey(key string) bool {
+	switch key {
+	case "logger", zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.MessageFieldName, zerolog.CallerFieldName:
+		return true
+	}
+	return false
+}
+
+// isSlogReservedKey also protects the keys slog handlers write themselves.
+func isSlogReservedKey(key string) bool {
+	switch key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	}
+	return isReservedKey(key)
+}
+
+func keyString(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
+
+// sanitizeKeys applies the key policy to a normalized key/value list, treating
+// the keys for which reserved returns true as reserved. args is returned as
+// is when no key needs attention.
+func sanitizeKeys(args []interface{}, reserved func(string) bool) []interface{} {
+	if !hasKeyConflicts(args, reserved) {
+		return args
+	}
+	return applyKeyPolicy(args, reserved, KeyPolicy(keyPolicy.Load()), currentKeyPrefix())
+}
+
+// hasKeyConflicts reports whether args holds duplicate keys or, unless
+// reserved is nil, keys for which reserved returns true. Groups are checked
+// for duplicates only.
+func hasKeyConflicts(args []interface{}, reserved func(string) bool) bool {
+	for i := 0; i+1 < len(args); i += 2 {
+		key := keyString(args[i])
+		if reserved != nil && reserved(key) {
+			return true
+		}
+		for j := 0; j < i; j += 2 {
+			if keyString(args[j]) == key {
+				return true
+			}
+		}
+		if g, ok := args[i+1].(group); ok && hasKeyConflicts(g, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyKeyPolicy(args []interface{}, reserved func(string) bool, policy KeyPolicy, prefix string) []interface{} {
+	result := make([]interface{}, 0, len(args))
+	index := make(map[string]int, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		key := keyString(args[i])
+		value := args[i+1]
+		if g, ok := value.(group); ok {
+			value = group(applyKeyPolicy(g, nil, policy, prefix))
+		}
+		isReserved := reserved != nil && reserved(key)
+		pos, duplicate := index[key]
+		if !isReserved && !duplicate {
+			index[key] = len(result)
+			result = append(result, key, value)
+			continue
+		}
+		switch policy {
+		case KeyPolicyRename:
+			for {
+				key = prefix + key
+				if _, taken := index[key]; !taken {
+					break
+				}
+			}
+			index[key] = len(result)
+			result = append(result, key, value)
+		case KeyPolicyLastWins:
+			if duplicate && !isReserved {
+				result[pos+1] = nil
+				result[pos] = nil
+				index[key] = len(result)
+				result = append(result, key, value)
+			}
+		case KeyPolicyReject:
+			if isReserved {
+				reportError(fmt.Errorf("reserved key %q rejected", key))
+			} else {
+				reportError(fmt.Errorf("duplicate key %q rejected", key))
+			}
+		}
+	}
+	// drop the slots freed by KeyPolicyLastWins
+	compacted := result[:0]
+	for i := 0; i+1 < len(result); i += 2 {
+		if result[i] != nil {
+			compacted = append(compacted, result[i], result[i+1])
+		}
+	}
+	return compacted
+}
+
+// endregion
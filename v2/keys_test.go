@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"testing"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	defer SetKeyPolicy(KeyPolicyRename)
+	defer SetErrorHandler(defaultErrorHandler)
+	bound := []interface{}{"user", "bound"}
+	args := []interface{}{"level", "debug", "user", "call", "n", 1}
+	tests := []struct {
+		policy   KeyPolicy
+		expected string
+		rejected int
+	}{
+		{KeyPolicyRename, "[user bound fields.level debug fields.user call n 1]", 0},
+		{KeyPolicyLastWins, "[user call n 1]", 0},
+		{KeyPolicyFirstWins, "[user bound n 1]", 0},
+		{KeyPolicyReject, "[user bound n 1]", 2},
+	}
+	for _, test := range tests {
+		rejected := 0
+		SetErrorHandler(func(err error) { rejected++ })
+		SetKeyPolicy(test.policy)
+		result := fmt.Sprint(prepareArgs(zerolog.InfoLevel, isReservedKey, bound, nil, args))
+		if result != test.expected || rejected != test.rejected {
+			t.Errorf("policy %d: got %s (%d rejected), expected %s (%d rejected)",
+				test.policy, result, rejected, test.expected, test.rejected)
+		}
+	}
+}
+
+func TestKeyPolicyGroups(t *testing.T) {
+	result := fmt.Sprint(prepareArgs(zerolog.InfoLevel, isReservedKey, nil, []string{"db"}, []interface{}{"level", 1, "level", 2}))
+	if result != "[db [level 1 fields.level 2]]" {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
+func TestKeyPolicySlog(t *testing.T) {
+	args := []interface{}{"msg", "a", "source", "b", "message", "c", "n", 1}
+	result := fmt.Sprint(prepareArgs(zerolog.InfoLevel, isSlogReservedKey, nil, nil, args))
+	if result != "[fields.msg a fields.source b fields.message c n 1]" {
+		t.Errorf("unexpected result: %s", result)
+	}
+	if result := fmt.Sprint(prepareArgs(zerolog.InfoLevel, isReservedKey, nil, nil, args)); result != "[msg a source b fields.message c n 1]" {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
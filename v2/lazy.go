@@ -0,0 +1,24 @@
+package logging
+
+// region - lazy values
+
+// LazyValue is a field value that is computed only when the entry is actually
+// written, i.e. after the level check has passed. It is resolved at most once
+// per entry.
+type LazyValue interface {
+	Resolve() interface{}
+}
+
+// Lazy adapts a plain function to LazyValue:
+//
+//	l.Debug("state dump", "state", logging.Lazy(func() interface{} { return expensive() }))
+type Lazy func() interface{}
+
+func (f Lazy) Resolve() interface{} {
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// endregion
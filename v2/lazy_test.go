@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLazyValue(t *testing.T) {
+	var lines []string
+	l := GetCustomLogger("lazy", func(msg string) { lines = append(lines, msg) })
+	defer DeleteCustomLogger("lazy")
+	l.SetLevel("info")
+
+	calls := 0
+	value := Lazy(func() interface{} {
+		calls++
+		return "expensive"
+	})
+
+	l.Debug("skipped", "v", value)
+	if calls != 0 {
+		t.Fatalf("lazy value resolved for disabled level: %d calls", calls)
+	}
+	l.Info("written", "v", value)
+	if calls != 1 {
+		t.Fatalf("expected exactly one resolution, got %d", calls)
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], "v=expensive") {
+		t.Fatalf("unexpected output: %v", lines)
+	}
+}
@@ -0,0 +1,227 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// region - temporary levels
+
+// LevelOverride describes a temporary level set with SetLevelFor.
+type LevelOverride struct {
+	ID      string    `json:"id"`
+	Level   string    `json:"level"`
+	Expires time.Time `json:"expires"`
+}
+
+type levelOverride struct {
+	LevelOverride
+	timer  *time.Timer
+	levels []*levelVar
+}
+
+var levelOverrides struct {
+	mu     sync.Mutex
+	active map[string]*levelOverride
+}
+
+// levelVar holds the level of a logger: the configured level, set by the
+// options, the environment or SetLevel, and the temporary level of
+// SetLevelFor, which wins while it is set. Loggers derived with WithGroup and
+// AddCallerSkip share the levelVar of their parent. Both levels change while
+// the logger is in use, so they are stored atomically.
+type levelVar struct {
+	level    atomic.Int32
+	override atomic.Pointer[zerolog.Level]
+}
+
+func newLevelVar(level zerolog.Level) *levelVar {
+	v := &levelVar{}
+	v.level.Store(int32(level))
+	return v
+}
+func (v *levelVar) get() zerolog.Level {
+	if o := v.override.Load(); o != nil {
+		return *o
+	}
+	return zerolog.Level(v.level.Load())
+}
+func (v *levelVar) set(level zerolog.Level) {
+	v.level.Store(int32(level))
+}
+
+// setLevel parses level for SetLevel; unknown levels fall back to info.
+func (v *levelVar) setLevel(level string) {
+	lvl, err := stringToLevel(level)
+	if err != nil {
+		lvl = zerolog.InfoLevel
+	}
+	v.set(lvl)
+}
+
+// SetLevelFor sets the level of every logger with the given id for duration d,
+// after which the loggers return to their configured level; SetLevel calls
+// made in the meantime change the configured level without ending the
+// override. Setting a new level for an id with an active override replaces
+// the level and the timer.
+func SetLevelFor(id string, level string, d time.Duration) error {
+	lvl, err := stringToLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid level %q", level)
+	}
+	if d <= 0 {
+		return errors.New("duration must be positive")
+	}
+	var levels []*levelVar
+	for _, l := range factoryLoggers(id) {
+		if v, ok := l.(interface{ loggerLevel() *levelVar }); ok {
+			levels = append(levels, v.loggerLevel())
+		}
+	}
+	if len(levels) == 0 {
+		return fmt.Errorf("logger %q not found", id)
+	}
+
+	levelOverrides.mu.Lock()
+	defer levelOverrides.mu.Unlock()
+	if levelOverrides.active == nil {
+		levelOverrides.active = make(map[string]*levelOverride)
+	}
+	if o, ok := levelOverrides.active[id]; ok {
+		o.timer.Stop()
+	}
+	for _, v := range levels {
+		v.override.Store(&lvl)
+	}
+	o := &levelOverride{
+		LevelOverride: LevelOverride{ID: id, Level: level, Expires: time.Now().Add(d)},
+		levels:        levels,
+	}
+	o.timer = time.AfterFunc(d, func() {
+		revertLevel(id, o)
+	})
+	levelOverrides.active[id] = o
+	return nil
+}
+
+// CancelLevelFor ends the temporary level of a logger right away. It returns
+// false if the logger has no active override.
+func CancelLevelFor(id string) bool {
+	levelOverrides.mu.Lock()
+	o, ok := levelOverrides.active[id]
+	levelOverrides.mu.Unlock()
+	if !ok {
+		return false
+	}
+	o.timer.Stop()
+	return revertLevel(id, o)
+}
+
+// LevelOverrides returns the active temporary levels ordered by logger id.
+func LevelOverrides() []LevelOverride {
+	levelOverrides.mu.Lock()
+	defer levelOverrides.mu.Unlock()
+	result := make([]LevelOverride, 0, len(levelOverrides.active))
+	for _, o := range levelOverrides.active {
+		result = append(result, o.LevelOverride)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func revertLevel(id string, o *levelOverride) bool {
+	levelOverrides.mu.Lock()
+	defer levelOverrides.mu.Unlock()
+	if levelOverrides.active[id] != o {
+		return false
+	}
+	delete(levelOverrides.active, id)
+	for _, v := range o.levels {
+		v.override.Store(nil)
+	}
+	return true
+}
+
+// factoryLoggers returns the loggers of all kinds registered with the id.
+func factoryLoggers(id string) []Logger {
+	loggerFactory.mutex.RLock()
+	defer loggerFactory.mutex.RUnlock()
+	var result []Logger
+	for _, m := range []map[string]Logger{
+		loggerFactory.consoleLoggers,
+		loggerFactory.fileLoggers,
+		loggerFactory.customLoggers,
+		loggerFactory.slogLoggers,
+	} {
+		if l, ok := m[id]; ok {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// endregion
+
+// region - admin API
+
+type levelRequest struct {
+	ID       string `json:"id"`
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+// LevelHandler serves the temporary levels over HTTP: GET lists the active
+// overrides, POST sets one from a JSON body such as
+// {"id":"db","level":"debug","duration":"15m"} and DELETE with an id query
+// parameter cancels one. It does no authentication of its own, so it belongs
+// behind the admin endpoints of the application.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeLevelResponse(w, http.StatusOK, LevelOverrides())
+		case http.MethodPost:
+			var req levelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid duration %q", req.Duration), http.StatusBadRequest)
+				return
+			}
+			if err := SetLevelFor(req.ID, req.Level, d); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeLevelResponse(w, http.StatusOK, LevelOverrides())
+		case http.MethodDelete:
+			if !CancelLevelFor(r.URL.Query().Get("id")) {
+				http.Error(w, "no active override", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeLevelResponse(w http.ResponseWriter, status int, overrides []LevelOverride) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(overrides)
+}
+
+// endregion
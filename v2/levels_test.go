@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	l := GetCustomLogger("level-for", func(string) {}).SetLevel("info")
+	defer DeleteCustomLogger("level-for")
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// log while the timer reverts the level
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				l.Debug("concurrent")
+			}
+		}
+	}()
+	if err := SetLevelFor("level-for", "debug", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if l.GetLevel() != "debug" {
+		t.Fatalf("expected debug, got %s", l.GetLevel())
+	}
+	if o := LevelOverrides(); len(o) != 1 || o[0].ID != "level-for" || o[0].Level != "debug" {
+		t.Fatalf("unexpected overrides: %v", o)
+	}
+	if err := SetLevelFor("level-for", "trace", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if l.GetLevel() != "info" {
+		t.Fatalf("expected the level to revert to info, got %s", l.GetLevel())
+	}
+	if o := LevelOverrides(); len(o) != 0 {
+		t.Fatalf("unexpected overrides: %v", o)
+	}
+}
+
+func TestCancelLevelFor(t *testing.T) {
+	l := GetLogger("level-cancel").SetLevel("warn")
+	defer DeleteLogger("level-cancel")
+	if err := SetLevelFor("level-cancel", "trace", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsTraceEnabled() {
+		t.Fatal("expected trace to be enabled")
+	}
+	l.SetLevel("error")
+	if !l.IsTraceEnabled() {
+		t.Fatal("expected the override to outlast SetLevel")
+	}
+	if !CancelLevelFor("level-cancel") || CancelLevelFor("level-cancel") {
+		t.Fatal("expected exactly one cancellation")
+	}
+	if l.GetLevel() != "error" {
+		t.Fatalf("expected the configured level error, got %s", l.GetLevel())
+	}
+	if err := SetLevelFor("level-missing", "debug", time.Hour); err == nil {
+		t.Fatal("expected an error for an unknown logger")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	l := GetCustomLogger("level-admin", func(string) {}).SetLevel("info")
+	defer DeleteCustomLogger("level-admin")
+	h := LevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/levels", strings.NewReader(`{"id":"level-admin","level":"debug","duration":"1m"}`)))
+	var overrides []LevelOverride
+	if err := json.Unmarshal(w.Body.Bytes(), &overrides); w.Code != http.StatusOK || err != nil || len(overrides) != 1 || overrides[0].ID != "level-admin" {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+	}
+	if l.GetLevel() != "debug" {
+		t.Fatalf("expected debug, got %s", l.GetLevel())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/levels", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"debug"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/levels?id=level-admin", nil))
+	if w.Code != http.StatusNoContent || l.GetLevel() != "info" {
+		t.Fatalf("unexpected response %d, level %s", w.Code, l.GetLevel())
+	}
+
+	for _, body := range []string{`{"id":"level-admin","level":"debug","duration":"soon"}`, `{"id":"missing","level":"debug","duration":"1m"}`, `{`} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/levels", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/levels?id=level-admin", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
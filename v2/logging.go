@@ -1,18 +1,33 @@
 package logging
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Logger is implemented by every backend of the package. Fatal and Panic
+// behave the same everywhere: Fatal writes the entry (when the Fatal level is
+// enabled), runs the exit hooks, calls Shutdown and finally the exit function
+// (os.Exit unless replaced with SetExitFunc) with code 1. Panic writes the
+// entry (when the Panic level is enabled) and then calls the panic function
+// (see SetPanicFunc) with the message. The exit and the panic happen even if
+// the level is disabled, so the control flow of the caller never depends on
+// the logging configuration.
 type Logger interface {
 	Clone(newId string) Logger
+	WithGroup(name string) Logger
+	// WithContext returns a logger that binds the fields carried by ctx; a
+	// context marked with WithDebugSession lifts the level threshold.
+	WithContext(ctx context.Context) Logger
 	Trace(message string, args ...interface{})
 	Debug(message string, args ...interface{})
 	Info(message string, args ...interface{})
@@ -37,6 +52,7 @@ func init() {
 		consoleLoggers: make(map[string]Logger),
 		fileLoggers:    make(map[string]Logger),
 		customLoggers:  make(map[string]Logger),
+		slogLoggers:    make(map[string]Logger),
 	}
 }
 
@@ -48,6 +64,7 @@ type loggerFactoryImpl struct {
 	consoleLoggers map[string]Logger
 	fileLoggers    map[string]Logger
 	customLoggers  map[string]Logger
+	slogLoggers    map[string]Logger
 	mutex          sync.RWMutex
 }
 
@@ -67,46 +84,69 @@ func GetLogger(id string, opts ...Option) Logger {
 	loggerFactory.mutex.Unlock()
 	return l
 }
-func GetCustomLogger(id string, logFn func(msg string) /*, opts ...Option*/) Logger {
+func GetCustomLogger(id string, logFn func(msg string), opts ...Option) Logger {
 	loggerFactory.mutex.RLock()
 	v, ok := loggerFactory.customLoggers[id]
 	loggerFactory.mutex.RUnlock()
 	if ok {
 		return v
 	}
-	l := newCustomLogger(id, logFn /*, opts...*/)
+	l := newCustomLogger(id, logFn, opts...)
 	loggerFactory.mutex.Lock()
 	loggerFactory.customLoggers[id] = l
 	loggerFactory.mutex.Unlock()
 	return l
 }
-func GetCustomLoggerWithTimestamp(id string, logFn func(msg string) /*, opts ...Option*/) Logger {
+func GetCustomLoggerWithTimestamp(id string, logFn func(msg string), opts ...Option) Logger {
 	loggerFactory.mutex.RLock()
 	v, ok := loggerFactory.customLoggers[id]
 	loggerFactory.mutex.RUnlock()
 	if ok {
 		return v
 	}
-	l := newCustomLoggerWithTimestamp(id, logFn /*, opts...*/)
+	l := newCustomLoggerWithTimestamp(id, logFn, opts...)
 	loggerFactory.mutex.Lock()
 	loggerFactory.customLoggers[id] = l
 	loggerFactory.mutex.Unlock()
 	return l
 }
+func GetSlogLogger(id string, handler slog.Handler, opts ...Option) Logger {
+	loggerFactory.mutex.RLock()
+	v, ok := loggerFactory.slogLoggers[id]
+	loggerFactory.mutex.RUnlock()
+	if ok {
+		return v
+	}
+	l := newSlogLogger(id, handler, opts...)
+	loggerFactory.mutex.Lock()
+	loggerFactory.slogLoggers[id] = l
+	loggerFactory.mutex.Unlock()
+	return l
+}
 func DeleteLogger(id string) {
 	loggerFactory.mutex.Lock()
 	delete(loggerFactory.consoleLoggers, id)
 	loggerFactory.mutex.Unlock()
 }
-func GetFileLogger(file *os.File, id string) Logger {
+func DeleteCustomLogger(id string) {
+	loggerFactory.mutex.Lock()
+	delete(loggerFactory.customLoggers, id)
+	loggerFactory.mutex.Unlock()
+}
+func DeleteSlogLogger(id string) {
+	loggerFactory.mutex.Lock()
+	delete(loggerFactory.slogLoggers, id)
+	loggerFactory.mutex.Unlock()
+}
+func GetFileLogger(file *os.File, id string, opts ...Option) Logger {
 	loggerFactory.mutex.Lock()
 	defer loggerFactory.mutex.Unlock()
 	v, ok := loggerFactory.fileLoggers[id]
 	if ok {
 		return v
 	}
-	l := newCommonFileLogger(file, id)
-	loggerFactory.consoleLoggers[id] = l
+	l := newCommonFileLogger(file, id, opts...)
+	loggerFactory.fileLoggers[id] = l
 	return l
 }
 func DeleteFileLogger(id string) {
@@ -119,6 +159,40 @@ func DeleteFileLogger(id string) {
 	}
 }
 
+// Shutdown closes all outputs and the files of all file loggers. It is meant
+// to be called once, right before the process exits.
+func Shutdown() {
+	closeOutputs()
+	loggerFactory.mutex.Lock()
+	defer loggerFactory.mutex.Unlock()
+	for id, v := range loggerFactory.fileLoggers {
+		v.(*fileLogger).Close()
+		delete(loggerFactory.fileLoggers, id)
+	}
+}
+
+func defaultErrorHandler(err error) {
+	fmt.Fprintf(os.Stderr, "logging: %v\n", err)
+}
+
+var errorHandler atomic.Pointer[func(err error)]
+
+// SetErrorHandler sets the function called for errors the logging package
+// cannot report through a logger, e.g. rejected fields. The default handler
+// prints to stderr. It is safe to call while logging.
+func SetErrorHandler(handler func(err error)) {
+	if handler != nil {
+		errorHandler.Store(&handler)
+	}
+}
+func reportError(err error) {
+	if handler := errorHandler.Load(); handler != nil {
+		(*handler)(err)
+		return
+	}
+	defaultErrorHandler(err)
+}
+
 // endregion
 
 // region - common
@@ -148,7 +222,6 @@ func configureConsoleWriter(id string) io.Writer {
 		},
 		PartsExclude: []string{
 			//zerolog.TimestampFieldName,
-			zerolog.CallerFieldName,
 			"logger",
 		},
 	}
@@ -1,153 +1,182 @@
 package logging
 
 import (
-	"fmt"
+	"context"
 	"github.com/rs/zerolog"
-	"strings"
 	"sync"
 	"time"
 )
 
 type customLogger struct {
-	mu               sync.Mutex
-	logFn            func(string)
-	logger           string
-	level            zerolog.Level
-	includeTimestamp bool
+	mu         *sync.Mutex
+	logFn      func(string)
+	logger     string
+	level      *levelVar
+	format     textFormat
+	fields     []interface{}
+	hooks      []zerolog.Hook
+	outputs    []Output
+	groups     []string
+	scope      scoped
+	caller     CallerFormat
+	callerSkip int
 }
 
 func newCustomLogger(id string, logFn func(string), opts ...Option) Logger {
-	return &customLogger{
-		level:            getLoggingLevel(id),
-		logger:           id,
-		logFn:            logFn,
-		includeTimestamp: false,
-	}
+	return newCommonCustomLogger(id, logFn, false, opts...)
 }
 func newCustomLoggerWithTimestamp(id string, logFn func(string), opts ...Option) Logger {
+	return newCommonCustomLogger(id, logFn, true, opts...)
+}
+func newCommonCustomLogger(id string, logFn func(string), timestamp bool, opts ...Option) Logger {
+	o := newOptions(opts...)
 	return &customLogger{
-		level:            getLoggingLevel(id),
-		logger:           id,
-		logFn:            logFn,
-		includeTimestamp: true,
+		mu:         &sync.Mutex{},
+		level:      newLevelVar(o.loggingLevel(id)),
+		logger:     id,
+		logFn:      logFn,
+		format:     newTextFormat(o, o.includeTimestamp(timestamp)),
+		fields:     o.fields,
+		outputs:    o.outputs,
+		hooks:      o.hooks,
+		caller:     o.caller,
+		callerSkip: o.callerSkip,
 	}
 }
 
 func (l *customLogger) Clone(newId string) Logger {
-	return &customLogger{
-		level:  getLoggingLevel(newId),
-		logger: newId,
-		logFn:  l.logFn,
+	c := *l
+	c.level = newLevelVar(getLoggingLevel(newId))
+	c.logger = newId
+	c.groups = nil
+	return &c
+}
+func (l *customLogger) WithGroup(name string) Logger {
+	if name == "" {
+		return l
+	}
+	c := *l
+	c.groups = appendGroup(l.groups, name)
+	return &c
+}
+func (l *customLogger) WithContext(ctx context.Context) Logger {
+	lc := newLoggerContext(ctx)
+	if lc.empty() {
+		return l
 	}
+	c := *l
+	c.fields = appendFields(l.fields, lc.fields)
+	level, scope := lc.apply(l.level.get(), l.scope)
+	if level != l.level.get() {
+		c.level = newLevelVar(level)
+	}
+	c.scope = scope
+	return &c
+}
+func (l *customLogger) withCallerSkip(skip int) Logger {
+	c := *l
+	c.callerSkip += skip
+	return &c
 }
 
 func (l *customLogger) Trace(message string, args ...interface{}) {
-	if l.IsTraceEnabled() {
+	if l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip) {
 		l.log(zerolog.TraceLevel, message, args...)
 	}
 }
 func (l *customLogger) Debug(message string, args ...interface{}) {
-	if l.IsDebugEnabled() {
+	if l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip) {
 		l.log(zerolog.DebugLevel, message, args...)
 	}
 }
 func (l *customLogger) Info(message string, args ...interface{}) {
-	if l.IsInfoEnabled() {
+	if l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip) {
 		l.log(zerolog.InfoLevel, message, args...)
 	}
 }
 func (l *customLogger) Warning(message string, args ...interface{}) {
-	if l.IsWarningEnabled() {
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
 		l.log(zerolog.WarnLevel, message, args...)
 	}
 }
 func (l *customLogger) Warn(message string, args ...interface{}) {
-	l.Warning(message, args...)
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
+	}
 }
 func (l *customLogger) Error(message string, args ...interface{}) {
-	if l.IsErrorEnabled() {
+	if l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip) {
 		l.log(zerolog.ErrorLevel, message, args...)
 	}
 }
 func (l *customLogger) Fatal(message string, args ...interface{}) {
-	if l.IsFatalEnabled() {
+	if l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip) {
 		l.log(zerolog.FatalLevel, message, args...)
 	}
+	exit(1)
 }
 func (l *customLogger) Panic(message string, args ...interface{}) {
-	if l.IsPanicEnabled() {
+	if l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip) {
 		l.log(zerolog.PanicLevel, message, args...)
 	}
+	panicFunc(message)
 }
 
 func (l *customLogger) IsTraceEnabled() bool {
-	return l.level == zerolog.TraceLevel
+	return l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsDebugEnabled() bool {
-	return l.level <= zerolog.DebugLevel
+	return l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsInfoEnabled() bool {
-	return l.level <= zerolog.InfoLevel
+	return l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsWarningEnabled() bool {
-	return l.level <= zerolog.WarnLevel
+	return l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsErrorEnabled() bool {
-	return l.level <= zerolog.ErrorLevel
+	return l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsFatalEnabled() bool {
-	return l.level <= zerolog.FatalLevel
+	return l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip)
 }
 func (l *customLogger) IsPanicEnabled() bool {
-	return l.level <= zerolog.PanicLevel
+	return l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip)
 }
 
 func (l *customLogger) SetLevel(level string) Logger {
-	var err error
-	l.level, err = stringToLevel(level)
-	if err != nil {
-		l.level = zerolog.InfoLevel
-	}
+	l.level.setLevel(level)
 	return l
 }
 func (l *customLogger) GetLevel() string {
-	return l.level.String()
+	return l.level.get().String()
+}
+func (l *customLogger) loggerLevel() *levelVar {
+	return l.level
 }
 
 func (l *customLogger) log(level zerolog.Level, message string, args ...interface{}) {
+	if l.logFn == nil && len(l.outputs) == 0 {
+		return
+	}
+	args = withCaller(caller(l.caller, callerSkip+l.callerSkip), prepareArgs(level, isReservedKey, l.fields, l.groups, args))
+	args, ok := runHooks(l.hooks, level, message, args, isReservedKey)
+	if !ok {
+		return
+	}
+	t := time.Now()
+	if l.scope.buffer(level, message, args, func() { l.write(t, level, message, args) }) {
+		return
+	}
+	l.write(t, level, message, args)
+}
+func (l *customLogger) write(t time.Time, level zerolog.Level, message string, args []interface{}) {
+	writeOutputs(l.outputs, t, level, l.logger, message, args)
 	if l.logFn == nil {
 		return
 	}
-	msg := l.format(level, message, args...)
+	msg := l.format.format(t, level, l.logger, message, args...)
 	l.mu.Lock()
 	l.logFn(msg)
 	l.mu.Unlock()
 }
-func (l *customLogger) format(level zerolog.Level, message string, args ...interface{}) string {
-	if l.includeTimestamp {
-		return fmt.Sprintf(
-			fileLogFormat,
-			time.Now().Format("2006-01-02 15:04:05.000"),
-			logLevelAbbr(level),
-			l.logger,
-			message,
-			l.args(args...),
-		)
-	}
-	return fmt.Sprintf(
-		fileLogFormatWithoutTs,
-		logLevelAbbr(level),
-		l.logger,
-		message,
-		l.args(args...),
-	)
-}
-func (l *customLogger) args(args ...interface{}) string {
-	var sb strings.Builder
-	for i := 0; i < len(args); i += 2 {
-		if i+1 < len(args) {
-			sb.WriteString(fmt.Sprintf(" %v=%v", args[i], args[i+1]))
-		}
-	}
-	return sb.String()
-}
@@ -18,6 +18,11 @@ func TestCustomLogger(t *testing.T) {
 	l.Info("hello info")
 	l.Warning("hello warn", "a", 1, "b", "2")
 	l.Error("hello error")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Panic did not panic")
+		}
+	}()
 	l.Clone("another").Panic("hello panic", "a", 1, "b", "2")
 }
 
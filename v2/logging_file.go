@@ -1,11 +1,11 @@
 package logging
 
 import (
+	"context"
 	"fmt"
 	"github.com/rs/zerolog"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 )
@@ -34,29 +34,54 @@ func getMutex(file *os.File) *sync.Mutex {
 	return v
 }
 
-func newCommonFileLogger(f *os.File, id string) Logger {
+func newCommonFileLogger(f *os.File, id string, opts ...Option) Logger {
+	o := newOptions(opts...)
 	return &fileLogger{
-		level:  getLoggingLevel(id),
-		logger: id,
-		file:   f,
+		level:      newLevelVar(o.loggingLevel(id)),
+		logger:     id,
+		file:       f,
+		format:     newTextFormat(o, o.includeTimestamp(true)),
+		fields:     o.fields,
+		outputs:    o.outputs,
+		hooks:      o.hooks,
+		caller:     o.caller,
+		callerSkip: o.callerSkip,
 	}
 }
 
-const fileLogFormatWithoutTs = "%3s [%s] %s %s"
-const fileLogFormat = "%s %3s [%s] %s %s"
-
 type fileLogger struct {
 	//mutex  sync.Mutex
-	level  zerolog.Level
-	file   *os.File
-	logger string
+	level      *levelVar
+	file       *os.File
+	logger     string
+	format     textFormat
+	fields     []interface{}
+	hooks      []zerolog.Hook
+	outputs    []Output
+	groups     []string
+	scope      scoped
+	caller     CallerFormat
+	callerSkip int
 }
 
 func (l *fileLogger) log(level zerolog.Level, message string, args ...interface{}) {
-	if l.file == nil {
+	if l.file == nil && len(l.outputs) == 0 {
+		return
+	}
+	args = withCaller(caller(l.caller, callerSkip+l.callerSkip), prepareArgs(level, isReservedKey, l.fields, l.groups, args))
+	args, ok := runHooks(l.hooks, level, message, args, isReservedKey)
+	if !ok {
+		return
+	}
+	t := time.Now()
+	if l.scope.buffer(level, message, args, func() { l.write(t, level, message, args) }) {
 		return
 	}
-	msg := l.format(level, message, args...)
+	l.write(t, level, message, args)
+}
+func (l *fileLogger) write(t time.Time, level zerolog.Level, message string, args []interface{}) {
+	writeOutputs(l.outputs, t, level, l.logger, message, args)
+	msg := l.format.format(t, level, l.logger, message, args...)
 	mtx := getMutex(l.file)
 	if mtx == nil {
 		return
@@ -68,101 +93,122 @@ func (l *fileLogger) log(level zerolog.Level, message string, args ...interface{
 	}
 	mtx.Unlock()
 }
-func (l *fileLogger) format(level zerolog.Level, message string, args ...interface{}) string {
-	return fmt.Sprintf(
-		fileLogFormat,
-		time.Now().Format("2006-01-02 15:04:05.000"),
-		logLevelAbbr(level),
-		l.logger,
-		message,
-		l.args(args...),
-	)
-}
-func (l *fileLogger) args(args ...interface{}) string {
-	var sb strings.Builder
-	for i := 0; i < len(args); i += 2 {
-		if i+1 < len(args) {
-			sb.WriteString(fmt.Sprintf(" %v=%v", args[i], args[i+1]))
-		}
-	}
-	return sb.String()
-}
-
 func (l *fileLogger) Close() {
-	l.file.Close()
+	if l.file == nil {
+		return
+	}
+	_ = l.file.Sync()
+	_ = l.file.Close()
 }
 func (l *fileLogger) Clone(newId string) Logger {
-	return GetFileLogger(l.file, newId)
+	c := *l
+	c.level = newLevelVar(getLoggingLevel(newId))
+	c.logger = newId
+	c.groups = nil
+	return &c
+}
+func (l *fileLogger) WithGroup(name string) Logger {
+	if name == "" {
+		return l
+	}
+	c := *l
+	c.groups = appendGroup(l.groups, name)
+	return &c
+}
+func (l *fileLogger) WithContext(ctx context.Context) Logger {
+	lc := newLoggerContext(ctx)
+	if lc.empty() {
+		return l
+	}
+	c := *l
+	c.fields = appendFields(l.fields, lc.fields)
+	level, scope := lc.apply(l.level.get(), l.scope)
+	if level != l.level.get() {
+		c.level = newLevelVar(level)
+	}
+	c.scope = scope
+	return &c
+}
+func (l *fileLogger) withCallerSkip(skip int) Logger {
+	c := *l
+	c.callerSkip += skip
+	return &c
 }
 
 func (l *fileLogger) Trace(message string, args ...interface{}) {
-	if l.level == zerolog.TraceLevel {
+	if l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip) {
 		l.log(zerolog.TraceLevel, message, args...)
 	}
 }
 func (l *fileLogger) Debug(message string, args ...interface{}) {
-	if l.level <= zerolog.DebugLevel {
+	if l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip) {
 		l.log(zerolog.DebugLevel, message, args...)
 	}
 }
 func (l *fileLogger) Info(message string, args ...interface{}) {
-	if l.level <= zerolog.InfoLevel {
+	if l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip) {
 		l.log(zerolog.InfoLevel, message, args...)
 	}
 }
 func (l *fileLogger) Warning(message string, args ...interface{}) {
-	if l.level <= zerolog.WarnLevel {
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
 		l.log(zerolog.WarnLevel, message, args...)
 	}
 }
 func (l *fileLogger) Warn(message string, args ...interface{}) {
-	l.Warning(message, args...)
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
+	}
 }
 func (l *fileLogger) Error(message string, args ...interface{}) {
-	if l.level <= zerolog.ErrorLevel {
+	if l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip) {
 		l.log(zerolog.ErrorLevel, message, args...)
 	}
 }
 func (l *fileLogger) Fatal(message string, args ...interface{}) {
-	if l.level <= zerolog.FatalLevel {
+	if l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip) {
 		l.log(zerolog.FatalLevel, message, args...)
 	}
+	exit(1)
 }
 func (l *fileLogger) Panic(message string, args ...interface{}) {
-	if l.level <= zerolog.PanicLevel {
+	if l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip) {
 		l.log(zerolog.PanicLevel, message, args...)
-		panic(fmt.Sprintf(message, args...))
 	}
+	panicFunc(message)
 }
 
 func (l *fileLogger) IsTraceEnabled() bool {
-	return l.level == zerolog.TraceLevel
+	return l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsDebugEnabled() bool {
-	return l.level <= zerolog.DebugLevel
+	return l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsInfoEnabled() bool {
-	return l.level <= zerolog.InfoLevel
+	return l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsWarningEnabled() bool {
-	return l.level <= zerolog.WarnLevel
+	return l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsErrorEnabled() bool {
-	return l.level <= zerolog.ErrorLevel
+	return l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsFatalEnabled() bool {
-	return l.level <= zerolog.FatalLevel
+	return l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip)
 }
 func (l *fileLogger) IsPanicEnabled() bool {
-	return l.level <= zerolog.PanicLevel
+	return l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip)
 }
 
 func (l *fileLogger) SetLevel(level string) Logger {
-	l.level = getLoggingLevel(level)
+	l.level.setLevel(level)
 	return l
 }
 func (l *fileLogger) GetLevel() string {
-	return l.level.String()
+	return l.level.get().String()
+}
+func (l *fileLogger) loggerLevel() *levelVar {
+	return l.level
 }
 
 // endregion
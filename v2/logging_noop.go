@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"context"
 	"github.com/rs/zerolog"
 )
 
@@ -12,6 +13,12 @@ type noOpLogger struct {
 func (l *noOpLogger) Clone(newId string) Logger {
 	return l
 }
+func (l *noOpLogger) WithGroup(name string) Logger {
+	return l
+}
+func (l *noOpLogger) WithContext(ctx context.Context) Logger {
+	return l
+}
 
 func (l *noOpLogger) Trace(message string, args ...interface{}) {
 }
@@ -26,8 +33,10 @@ func (l *noOpLogger) Warn(message string, args ...interface{}) {
 func (l *noOpLogger) Error(message string, args ...interface{}) {
 }
 func (l *noOpLogger) Fatal(message string, args ...interface{}) {
+	exit(1)
 }
 func (l *noOpLogger) Panic(message string, args ...interface{}) {
+	panicFunc(message)
 }
 
 func (l *noOpLogger) IsTraceEnabled() bool {
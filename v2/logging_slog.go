@@ -0,0 +1,247 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog"
+	"log/slog"
+	"time"
+)
+
+// region - slog
+
+const (
+	slogLevelTrace = slog.Level(-8)
+	slogLevelFatal = slog.Level(12)
+	slogLevelPanic = slog.Level(16)
+)
+
+func newSlogLogger(id string, handler slog.Handler, opts ...Option) Logger {
+	o := newOptions(opts...)
+	return &slogLogger{
+		level:      newLevelVar(o.loggingLevel(id)),
+		logger:     id,
+		handler:    handler,
+		timestamp:  o.includeTimestamp(true),
+		fields:     o.fields,
+		outputs:    o.outputs,
+		hooks:      o.hooks,
+		caller:     o.caller,
+		callerSkip: o.callerSkip,
+	}
+}
+
+type slogLogger struct {
+	level      *levelVar
+	logger     string
+	handler    slog.Handler
+	timestamp  bool
+	debug      bool
+	fields     []interface{}
+	hooks      []zerolog.Hook
+	outputs    []Output
+	groups     []string
+	scope      scoped
+	caller     CallerFormat
+	callerSkip int
+}
+
+func (l *slogLogger) Clone(newId string) Logger {
+	c := *l
+	c.level = newLevelVar(getLoggingLevel(newId))
+	c.logger = newId
+	c.groups = nil
+	return &c
+}
+func (l *slogLogger) WithGroup(name string) Logger {
+	if name == "" {
+		return l
+	}
+	c := *l
+	c.groups = appendGroup(l.groups, name)
+	return &c
+}
+func (l *slogLogger) WithContext(ctx context.Context) Logger {
+	lc := newLoggerContext(ctx)
+	if lc.empty() {
+		return l
+	}
+	c := *l
+	c.fields = appendFields(l.fields, lc.fields)
+	level, scope := lc.apply(l.level.get(), l.scope)
+	if level != l.level.get() {
+		c.level = newLevelVar(level)
+	}
+	c.scope = scope
+	c.debug = l.debug || lc.debug
+	return &c
+}
+func (l *slogLogger) withCallerSkip(skip int) Logger {
+	c := *l
+	c.callerSkip += skip
+	return &c
+}
+
+func (l *slogLogger) Trace(message string, args ...interface{}) {
+	if l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip) {
+		l.log(zerolog.TraceLevel, message, args...)
+	}
+}
+func (l *slogLogger) Debug(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip) {
+		l.log(zerolog.DebugLevel, message, args...)
+	}
+}
+func (l *slogLogger) Info(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip) {
+		l.log(zerolog.InfoLevel, message, args...)
+	}
+}
+func (l *slogLogger) Warning(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
+	}
+}
+func (l *slogLogger) Warn(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
+	}
+}
+func (l *slogLogger) Error(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip) {
+		l.log(zerolog.ErrorLevel, message, args...)
+	}
+}
+func (l *slogLogger) Fatal(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip) {
+		l.log(zerolog.FatalLevel, message, args...)
+	}
+	exit(1)
+}
+func (l *slogLogger) Panic(message string, args ...interface{}) {
+	if l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip) {
+		l.log(zerolog.PanicLevel, message, args...)
+	}
+	panicFunc(message)
+}
+
+func (l *slogLogger) IsTraceEnabled() bool {
+	return l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsDebugEnabled() bool {
+	return l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsInfoEnabled() bool {
+	return l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsWarningEnabled() bool {
+	return l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsErrorEnabled() bool {
+	return l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsFatalEnabled() bool {
+	return l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip)
+}
+func (l *slogLogger) IsPanicEnabled() bool {
+	return l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip)
+}
+
+func (l *slogLogger) SetLevel(level string) Logger {
+	l.level.setLevel(level)
+	return l
+}
+func (l *slogLogger) GetLevel() string {
+	return l.level.get().String()
+}
+func (l *slogLogger) loggerLevel() *levelVar {
+	return l.level
+}
+
+func (l *slogLogger) log(level zerolog.Level, message string, args ...interface{}) {
+	if l.handler == nil && len(l.outputs) == 0 {
+		return
+	}
+	lvl := slogLevel(level)
+	// entries of debug sessions bypass the level of the handler as well
+	if !l.debug && l.scope.scope == nil && len(l.outputs) == 0 && !l.handler.Enabled(context.Background(), lvl) {
+		return
+	}
+	// the record carries the call site, so handlers with AddSource report the
+	// caller of the Logger method
+	pc := callerPC(callerSkip + l.callerSkip)
+	args = withCaller(formatCaller(l.caller, pc), prepareArgs(level, isSlogReservedKey, l.fields, l.groups, args))
+	args, ok := runHooks(l.hooks, level, message, args, isSlogReservedKey)
+	if !ok {
+		return
+	}
+	t := time.Now()
+	if l.scope.buffer(level, message, args, func() { l.write(t, level, message, pc, args) }) {
+		return
+	}
+	l.write(t, level, message, pc, args)
+}
+func (l *slogLogger) write(t time.Time, level zerolog.Level, message string, pc uintptr, args []interface{}) {
+	writeOutputs(l.outputs, t, level, l.logger, message, args)
+	if l.handler == nil {
+		return
+	}
+	ctx := context.Background()
+	lvl := slogLevel(level)
+	if !l.debug && !l.handler.Enabled(ctx, lvl) {
+		return
+	}
+	// handlers leave out the time of records with a zero time
+	if !l.timestamp {
+		t = time.Time{}
+	}
+	r := slog.NewRecord(t, lvl, message, pc)
+	r.AddAttrs(slog.String("logger", l.logger))
+	for i := 0; i+1 < len(args); i += 2 {
+		r.AddAttrs(slog.Attr{Key: fmt.Sprint(args[i]), Value: slogValue(args[i+1])})
+	}
+	_ = l.handler.Handle(ctx, r)
+}
+
+func slogLevel(level zerolog.Level) slog.Level {
+	switch level {
+	case zerolog.TraceLevel:
+		return slogLevelTrace
+	case zerolog.DebugLevel:
+		return slog.LevelDebug
+	case zerolog.WarnLevel:
+		return slog.LevelWarn
+	case zerolog.ErrorLevel:
+		return slog.LevelError
+	case zerolog.FatalLevel:
+		return slogLevelFatal
+	case zerolog.PanicLevel:
+		return slogLevelPanic
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func slogValue(value interface{}) slog.Value {
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		enc := &slogObjectEncoder{}
+		v.MarshalLogObject(enc)
+		return slog.GroupValue(enc.attrs...)
+	case ArrayMarshaler:
+		return slog.AnyValue(plainValue(v))
+	case error:
+		return slog.StringValue(v.Error())
+	}
+	return slog.AnyValue(stringerValue(value))
+}
+
+type slogObjectEncoder struct {
+	attrs []slog.Attr
+}
+
+func (enc *slogObjectEncoder) Add(key string, value interface{}) {
+	enc.attrs = append(enc.attrs, slog.Attr{Key: key, Value: slogValue(value)})
+}
+
+// endregion
@@ -1,10 +1,13 @@
 package logging
 
 import (
+	"context"
+	"fmt"
 	"github.com/rs/zerolog"
 	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 // region - zerolog
@@ -16,96 +19,242 @@ func newZerologLogger(id string, opts ...Option) Logger {
 	} else {
 		w = os.Stdout
 	}
-	ctx := zerolog.New(w).Level(getLoggingLevel(id)).With().Str("logger", id).Timestamp()
-
-	for _, opt := range opts {
-		if opt != nil {
-			ctx = opt(ctx)
-		}
-	}
-
-	logger := ctx.Logger()
+	o := newOptions(opts...)
+	// the level is checked by the logger itself, see levelVar
+	logger := zerolog.New(w).With().Str("logger", id).Logger()
 	result := &zerologLogger{
-		lg: &logger,
+		lg:         &logger,
+		level:      newLevelVar(o.loggingLevel(id)),
+		w:          w,
+		hooks:      o.hooks,
+		logger:     id,
+		fields:     o.fields,
+		outputs:    o.outputs,
+		caller:     o.caller,
+		timestamp:  o.includeTimestamp(true),
+		timeFormat: o.timeFormat,
+		callerSkip: o.callerSkip,
 	}
 	return result
 }
 
 type zerologLogger struct {
-	lg *zerolog.Logger
+	lg         *zerolog.Logger
+	level      *levelVar
+	w          io.Writer
+	hooks      []zerolog.Hook
+	logger     string
+	fields     []interface{}
+	outputs    []Output
+	groups     []string
+	scope      scoped
+	timestamp  bool
+	timeFormat string
+	caller     CallerFormat
+	callerSkip int
 }
 
 func (l *zerologLogger) Clone(newId string) Logger {
-	return GetLogger(newId)
+	c := *l
+	logger := zerolog.New(l.w).With().Str("logger", newId).Logger()
+	c.lg = &logger
+	c.level = newLevelVar(getLoggingLevel(newId))
+	c.logger = newId
+	c.groups = nil
+	return &c
+}
+func (l *zerologLogger) WithGroup(name string) Logger {
+	if name == "" {
+		return l
+	}
+	c := *l
+	c.groups = appendGroup(l.groups, name)
+	return &c
+}
+func (l *zerologLogger) WithContext(ctx context.Context) Logger {
+	lc := newLoggerContext(ctx)
+	if lc.empty() {
+		return l
+	}
+	c := *l
+	c.fields = appendFields(l.fields, lc.fields)
+	level, scope := lc.apply(l.level.get(), l.scope)
+	if level != l.level.get() {
+		c.level = newLevelVar(level)
+	}
+	c.scope = scope
+	return &c
+}
+func (l *zerologLogger) withCallerSkip(skip int) Logger {
+	c := *l
+	c.callerSkip += skip
+	return &c
 }
 func (l *zerologLogger) Trace(message string, args ...interface{}) {
-	if l.lg.GetLevel() == zerolog.TraceLevel {
-		l.lg.Trace().Fields(args).Msg(message)
+	if l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip) {
+		l.log(zerolog.TraceLevel, message, args...)
 	}
 }
 func (l *zerologLogger) Debug(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.DebugLevel {
-		l.lg.Debug().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip) {
+		l.log(zerolog.DebugLevel, message, args...)
 	}
 }
 func (l *zerologLogger) Info(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.InfoLevel {
-		l.lg.Info().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip) {
+		l.log(zerolog.InfoLevel, message, args...)
 	}
 }
 func (l *zerologLogger) Warning(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.WarnLevel {
-		l.lg.Warn().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
 	}
 }
 func (l *zerologLogger) Warn(message string, args ...interface{}) {
-	l.Warning(message, args...)
+	if l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip) {
+		l.log(zerolog.WarnLevel, message, args...)
+	}
 }
 func (l *zerologLogger) Error(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.ErrorLevel {
-		l.lg.Error().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip) {
+		l.log(zerolog.ErrorLevel, message, args...)
 	}
 }
 func (l *zerologLogger) Fatal(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.FatalLevel {
-		l.lg.Fatal().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip) {
+		l.log(zerolog.FatalLevel, message, args...)
 	}
+	exit(1)
 }
 func (l *zerologLogger) Panic(message string, args ...interface{}) {
-	if l.lg.GetLevel() <= zerolog.PanicLevel {
-		l.lg.Panic().Fields(args).Msg(message)
+	if l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip) {
+		l.log(zerolog.PanicLevel, message, args...)
+	}
+	panicFunc(message)
+}
+
+func (l *zerologLogger) log(level zerolog.Level, message string, args ...interface{}) {
+	args = withCaller(caller(l.caller, callerSkip+l.callerSkip), prepareArgs(level, isReservedKey, l.fields, l.groups, args))
+	// hooks run here rather than on the zerolog logger, so that outputs see the
+	// fields they add and none of the entries they discard
+	args, ok := runHooks(l.hooks, level, message, args, isReservedKey)
+	if !ok {
+		return
+	}
+	t := zerolog.TimestampFunc()
+	if l.scope.buffer(level, message, args, func() { l.write(t, level, message, args) }) {
+		return
+	}
+	l.write(t, level, message, args)
+}
+
+// write writes the entry through WithLevel, which, unlike Fatal() and Panic(),
+// never exits or panics on its own.
+func (l *zerologLogger) write(t time.Time, level zerolog.Level, message string, args []interface{}) {
+	e := l.lg.WithLevel(level)
+	if l.timestamp && l.timeFormat != "" {
+		e = e.Str(zerolog.TimestampFieldName, t.Format(l.timeFormat))
+	} else if l.timestamp {
+		e = e.Time(zerolog.TimestampFieldName, t)
 	}
+	zerologFields(e, args).Msg(message)
+	writeOutputs(l.outputs, t, level, l.logger, message, args)
 }
 
 func (l *zerologLogger) IsTraceEnabled() bool {
-	return l.lg.GetLevel() == zerolog.TraceLevel
+	return l.level.get() == zerolog.TraceLevel || vmoduleEnabled(zerolog.TraceLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsDebugEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.DebugLevel
+	return l.level.get() <= zerolog.DebugLevel || vmoduleEnabled(zerolog.DebugLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsInfoEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.InfoLevel
+	return l.level.get() <= zerolog.InfoLevel || vmoduleEnabled(zerolog.InfoLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsWarningEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.WarnLevel
+	return l.level.get() <= zerolog.WarnLevel || vmoduleEnabled(zerolog.WarnLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsErrorEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.ErrorLevel
+	return l.level.get() <= zerolog.ErrorLevel || vmoduleEnabled(zerolog.ErrorLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsFatalEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.FatalLevel
+	return l.level.get() <= zerolog.FatalLevel || vmoduleEnabled(zerolog.FatalLevel, 1+l.callerSkip)
 }
 func (l *zerologLogger) IsPanicEnabled() bool {
-	return l.lg.GetLevel() <= zerolog.PanicLevel
+	return l.level.get() <= zerolog.PanicLevel || vmoduleEnabled(zerolog.PanicLevel, 1+l.callerSkip)
 }
 
 func (l *zerologLogger) SetLevel(level string) Logger {
-	v := l.lg.Level(getLoggingLevel(level))
-	l.lg = &v
+	l.level.setLevel(level)
 	return l
 }
 func (l *zerologLogger) GetLevel() string {
-	return l.lg.GetLevel().String()
+	return l.level.get().String()
+}
+func (l *zerologLogger) loggerLevel() *levelVar {
+	return l.level
+}
+
+// endregion
+
+// region - zerolog fields
+
+func zerologFields(e *zerolog.Event, args []interface{}) *zerolog.Event {
+	for i := 0; i+1 < len(args); i += 2 {
+		e = zerologField(e, fmt.Sprint(args[i]), args[i+1])
+	}
+	return e
+}
+func zerologField(e *zerolog.Event, key string, value interface{}) *zerolog.Event {
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		return e.Object(key, zerologObject{v})
+	case ArrayMarshaler:
+		return e.Array(key, zerologArray{v})
+	}
+	return e.Fields([]interface{}{key, stringerValue(value)})
+}
+
+type zerologObject struct {
+	m ObjectMarshaler
+}
+
+func (o zerologObject) MarshalZerologObject(e *zerolog.Event) {
+	o.m.MarshalLogObject(zerologObjectEncoder{e})
+}
+
+type zerologObjectEncoder struct {
+	e *zerolog.Event
+}
+
+func (enc zerologObjectEncoder) Add(key string, value interface{}) {
+	zerologField(enc.e, key, value)
+}
+
+type zerologArray struct {
+	m ArrayMarshaler
+}
+
+func (a zerologArray) MarshalZerologArray(arr *zerolog.Array) {
+	a.m.MarshalLogArray(zerologArrayEncoder{arr})
+}
+
+type zerologArrayEncoder struct {
+	a *zerolog.Array
+}
+
+func (enc zerologArrayEncoder) Append(value interface{}) {
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		enc.a.Object(zerologObject{v})
+	case ArrayMarshaler:
+		enc.a.Interface(plainValue(v))
+	case error:
+		enc.a.Err(v)
+	case string:
+		enc.a.Str(v)
+	default:
+		enc.a.Interface(stringerValue(v))
+	}
 }
 
 // endregion
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// region - marshalers
+
+// ObjectMarshaler is implemented by types that know how to log themselves as
+// a set of named fields. Each backend renders the result in its own way:
+// nested JSON for zerolog, flattened parent.child=value pairs for the text
+// backends and nested groups for slog.
+type ObjectMarshaler interface {
+	MarshalLogObject(enc ObjectEncoder)
+}
+
+// ArrayMarshaler is the ObjectMarshaler counterpart for list-like types.
+type ArrayMarshaler interface {
+	MarshalLogArray(enc ArrayEncoder)
+}
+
+type ObjectEncoder interface {
+	Add(key string, value interface{})
+}
+type ArrayEncoder interface {
+	Append(value interface{})
+}
+
+// textValue returns the string form of values implementing one of the
+// well-known interfaces: error, encoding.TextMarshaler and fmt.Stringer.
+func textValue(value interface{}) (string, bool) {
+	if isNilPointer(value) {
+		return "", false
+	}
+	switch v := value.(type) {
+	case error:
+		return v.Error(), true
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err != nil {
+			return fmt.Sprintf("!ERROR(%v)", err), true
+		}
+		return string(b), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// stringerValue converts fmt.Stringer values to strings unless the value has
+// a better representation of its own (JSON, text, error, time).
+func stringerValue(value interface{}) interface{} {
+	switch value.(type) {
+	case error, encoding.TextMarshaler, time.Duration, interface{ MarshalJSON() ([]byte, error) }:
+		return value
+	}
+	if s, ok := value.(fmt.Stringer); ok && !isNilPointer(value) {
+		return s.String()
+	}
+	return value
+}
+
+func isNilPointer(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// plainValue converts marshalers into maps and slices, for encoders that have
+// no native notion of nested values.
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		obj := mapEncoder{}
+		v.MarshalLogObject(obj)
+		return map[string]interface{}(obj)
+	case ArrayMarshaler:
+		arr := &sliceEncoder{}
+		v.MarshalLogArray(arr)
+		return arr.values
+	}
+	return stringerValue(value)
+}
+
+type mapEncoder map[string]interface{}
+
+func (m mapEncoder) Add(key string, value interface{}) {
+	m[key] = plainValue(value)
+}
+
+type sliceEncoder struct {
+	values []interface{}
+}
+
+func (s *sliceEncoder) Append(value interface{}) {
+	s.values = append(s.values, plainValue(value))
+}
+
+// endregion
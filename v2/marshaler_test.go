@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type testAddress struct {
+	city string
+	zip  int
+}
+
+func (a testAddress) MarshalLogObject(enc ObjectEncoder) {
+	enc.Add("city", a.city)
+	enc.Add("zip", a.zip)
+}
+
+type testUser struct {
+	name    string
+	address testAddress
+	roles   testRoles
+}
+
+func (u testUser) MarshalLogObject(enc ObjectEncoder) {
+	enc.Add("name", u.name)
+	enc.Add("address", u.address)
+	enc.Add("roles", u.roles)
+}
+
+type testRoles []string
+
+func (r testRoles) MarshalLogArray(enc ArrayEncoder) {
+	for _, v := range r {
+		enc.Append(v)
+	}
+}
+
+var marshalerTestUser = testUser{
+	name:    "john",
+	address: testAddress{city: "Berlin", zip: 10115},
+	roles:   testRoles{"admin", "dev"},
+}
+
+func TestObjectMarshalerText(t *testing.T) {
+	var out string
+	defer DeleteCustomLogger("marshaler-text")
+	GetCustomLogger("marshaler-text", func(msg string) { out = msg }).Info("user", "user", marshalerTestUser)
+	expected := " user.name=john user.address.city=Berlin user.address.zip=10115 user.roles.0=admin user.roles.1=dev"
+	if !strings.HasSuffix(out, expected) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestObjectMarshalerZerolog(t *testing.T) {
+	var buf bytes.Buffer
+	zl := zerolog.New(&buf)
+	zerologFields(zl.Info(), []interface{}{"user", marshalerTestUser}).Msg("user")
+	expected := `"user":{"name":"john","address":{"city":"Berlin","zip":10115},"roles":["admin","dev"]}`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestObjectMarshalerSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := GetSlogLogger("marshaler-slog", slog.NewJSONHandler(&buf, nil))
+	defer DeleteSlogLogger("marshaler-slog")
+	l.Info("user", "user", marshalerTestUser)
+	expected := `"user":{"name":"john","address":{"city":"Berlin","zip":10115},"roles":["admin","dev"]}`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
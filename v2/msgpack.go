@@ -0,0 +1,420 @@
+package logging
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// region - msgpack
+
+// msgpackEncoder appends MessagePack encoded values to a buffer; it covers
+// what the outputs need and nothing more.
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) nil() {
+	e.buf = append(e.buf, 0xc0)
+}
+
+func (e *msgpackEncoder) bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 0xc3)
+	} else {
+		e.buf = append(e.buf, 0xc2)
+	}
+}
+
+func (e *msgpackEncoder) int(v int64) {
+	switch {
+	case v >= 0:
+		e.uint(uint64(v))
+	case v >= -32:
+		e.buf = append(e.buf, byte(v))
+	case v >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(v))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(v))
+	}
+}
+
+func (e *msgpackEncoder) uint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.buf = append(e.buf, byte(v))
+	case v <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(v))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), v)
+	}
+}
+
+func (e *msgpackEncoder) float(v float64) {
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(v))
+}
+
+func (e *msgpackEncoder) string(v string) {
+	n := len(v)
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, v...)
+}
+
+func (e *msgpackEncoder) bytes(v []byte) {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+	e.buf = append(e.buf, v...)
+}
+
+func (e *msgpackEncoder) arrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xdc), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdd), uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) mapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xde), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdf), uint32(n))
+	}
+}
+
+// eventTime appends t as the EventTime extension of the Fluent forward
+// protocol: type 0 with seconds and nanoseconds.
+func (e *msgpackEncoder) eventTime(t time.Time) {
+	e.buf = append(e.buf, 0xd7, 0x00)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(t.Unix()))
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(t.Nanosecond()))
+}
+
+// value appends a field value; objects and arrays are encoded as maps and
+// arrays, values without a MessagePack type as their text.
+func (e *msgpackEncoder) value(value interface{}) {
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			e.int(i)
+		} else {
+			f, _ := n.Float64()
+			e.float(f)
+		}
+		return
+	}
+	switch v := plainValue(value).(type) {
+	case nil:
+		e.nil()
+	case bool:
+		e.bool(v)
+	case string:
+		e.string(v)
+	case []byte:
+		e.bytes(v)
+	case time.Duration:
+		e.string(v.String())
+	case time.Time:
+		e.string(v.Format(time.RFC3339Nano))
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		e.mapHeader(len(keys))
+		for _, key := range keys {
+			e.string(key)
+			e.value(v[key])
+		}
+	case []interface{}:
+		e.arrayHeader(len(v))
+		for _, item := range v {
+			e.value(item)
+		}
+	default:
+		if s, ok := textValue(v); ok {
+			e.string(s)
+			return
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			e.int(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			e.uint(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			e.float(rv.Float())
+		case reflect.Slice, reflect.Array:
+			items := make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).Interface()
+			}
+			e.value(items)
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				m := make(map[string]interface{}, rv.Len())
+				for _, key := range rv.MapKeys() {
+					m[key.String()] = rv.MapIndex(key).Interface()
+				}
+				e.value(m)
+				return
+			}
+			e.string(fmt.Sprint(v))
+		default:
+			e.string(fmt.Sprint(v))
+		}
+	}
+}
+
+// decodeMsgpack reads one value from r. Maps decode to map[string]interface{}
+// (with non-string keys formatted), integers to int64 or uint64, EventTime to
+// time.Time and other extensions to []byte. Arrays and maps nest at most
+// maxMsgpackDepth levels.
+func decodeMsgpack(r io.Reader) (interface{}, error) {
+	return decodeMsgpackValue(r, 0)
+}
+func decodeMsgpackValue(r io.Reader, depth int) (interface{}, error) {
+	b, err := readMsgpack(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return decodeMsgpackMap(r, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return decodeMsgpackArray(r, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		s, err := readMsgpack(r, int(c&0x1f))
+		return string(s), err
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(r, c-0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpack(r, n)
+	case 0xca:
+		b, err := readMsgpack(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := readMsgpack(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := readMsgpack(r, 1<<(c-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackUint(b), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		b, err := readMsgpack(r, 1<<(c-0xd0))
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*len(b)
+		return int64(msgpackUint(b)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeMsgpackExt(r, 1<<(c-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackLength(r, c-0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackExt(r, n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, c-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		s, err := readMsgpack(r, n)
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, c-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, c-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("msgpack: invalid type 0x%02x", c)
+}
+
+const (
+	maxMsgpackLength = 64 << 20
+	maxMsgpackDepth  = 32
+)
+
+func readMsgpack(r io.Reader, n int) ([]byte, error) {
+	if n > maxMsgpackLength {
+		return nil, errors.New("msgpack: value too large")
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+// readMsgpackLength reads a length of 1, 2 or 4 bytes for size 0, 1 or 2.
+func readMsgpackLength(r io.Reader, size byte) (int, error) {
+	b, err := readMsgpack(r, 1<<size)
+	if err != nil {
+		return 0, err
+	}
+	return int(msgpackUint(b)), nil
+}
+
+func msgpackUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func decodeMsgpackArray(r io.Reader, n int, depth int) ([]interface{}, error) {
+	if n > maxMsgpackLength {
+		return nil, errors.New("msgpack: array too large")
+	}
+	if depth >= maxMsgpackDepth {
+		return nil, errors.New("msgpack: nesting too deep")
+	}
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeMsgpackMap(r io.Reader, n int, depth int) (map[string]interface{}, error) {
+	if n > maxMsgpackLength {
+		return nil, errors.New("msgpack: map too large")
+	}
+	if depth >= maxMsgpackDepth {
+		return nil, errors.New("msgpack: nesting too deep")
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+	return m, nil
+}
+
+// decodeMsgpackAck reads a Forward protocol acknowledgement, a map with the
+// single entry "ack", and returns its chunk id. Other values are rejected
+// without being decoded.
+func decodeMsgpackAck(r io.Reader) (string, error) {
+	b, err := readMsgpack(r, 1)
+	if err != nil {
+		return "", err
+	}
+	if b[0] != 0x81 {
+		return "", fmt.Errorf("msgpack: unexpected acknowledgement type 0x%02x", b[0])
+	}
+	key, err := decodeMsgpackString(r)
+	if err != nil {
+		return "", err
+	}
+	if key != "ack" {
+		return "", fmt.Errorf("msgpack: unexpected acknowledgement key %q", key)
+	}
+	return decodeMsgpackString(r)
+}
+
+func decodeMsgpackString(r io.Reader) (string, error) {
+	b, err := readMsgpack(r, 1)
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch c := b[0]; {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c >= 0xd9 && c <= 0xdb:
+		if n, err = readMsgpackLength(r, c-0xd9); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("msgpack: expected a string, got type 0x%02x", c)
+	}
+	s, err := readMsgpack(r, n)
+	return string(s), err
+}
+
+func decodeMsgpackExt(r io.Reader, n int) (interface{}, error) {
+	b, err := readMsgpack(r, n+1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] == 0 && n == 8 {
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	}
+	return b[1:], nil
+}
+
+// endregion
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgpackEncoding(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		want  []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{7, []byte{0x07}},
+		{-3, []byte{0xfd}},
+		{200, []byte{0xcc, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{json.Number("12"), []byte{0x0c}},
+		{time.Second, []byte{0xa2, '1', 's'}},
+	} {
+		var enc msgpackEncoder
+		enc.value(test.value)
+		if !bytes.Equal(enc.buf, test.want) {
+			t.Errorf("%v: got % x, want % x", test.value, enc.buf, test.want)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	now := time.Unix(1700000000, 123456789)
+	var enc msgpackEncoder
+	enc.arrayHeader(9)
+	enc.int(math.MinInt64)
+	enc.uint(math.MaxUint64)
+	enc.int(-40000)
+	enc.string(long)
+	enc.bytes([]byte{1, 2, 3})
+	enc.eventTime(now)
+	enc.value(map[string]interface{}{"k": []interface{}{"v", false}})
+	enc.float(-0.25)
+	enc.nil()
+
+	got, err := decodeMsgpack(bytes.NewReader(enc.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		int64(math.MinInt64), uint64(math.MaxUint64), int64(-40000), long, []byte{1, 2, 3}, now,
+		map[string]interface{}{"k": []interface{}{"v", false}}, -0.25, nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v", got)
+	}
+	if _, err := decodeMsgpack(bytes.NewReader(enc.buf[:10])); err == nil {
+		t.Fatal("expected an error for truncated input")
+	}
+}
+
+func TestMsgpackDepth(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x91}, 100000)
+	if _, err := decodeMsgpack(bytes.NewReader(nested)); err == nil || !strings.Contains(err.Error(), "too deep") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMsgpackAck(t *testing.T) {
+	var enc msgpackEncoder
+	enc.value(map[string]interface{}{"ack": "c1"})
+	if ack, err := decodeMsgpackAck(bytes.NewReader(enc.buf)); err != nil || ack != "c1" {
+		t.Fatalf("unexpected ack %q: %v", ack, err)
+	}
+	for _, v := range []interface{}{[]interface{}{"ack"}, map[string]interface{}{"ack": 1}, map[string]interface{}{"id": "c1"}} {
+		var enc msgpackEncoder
+		enc.value(v)
+		if _, err := decodeMsgpackAck(bytes.NewReader(enc.buf)); err == nil {
+			t.Errorf("expected an error for %v", v)
+		}
+	}
+}
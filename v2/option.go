@@ -1,43 +1,103 @@
 package logging
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
 	"github.com/rs/zerolog"
-	"path/filepath"
 )
 
-type Option func(zerolog.Context) zerolog.Context
+// Option configures a logger. Options are backend neutral: every constructor
+// accepts them, and a backend ignores the ones it has no use for.
+type Option func(*options)
 
+type options struct {
+	level      *zerolog.Level
+	fields     []interface{}
+	caller     CallerFormat
+	callerSkip int
+	timestamp  *bool
+	timeFormat string
+	format     string
+	hooks      []zerolog.Hook
+	outputs    []Output
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		caller: getCallerFormat(),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// loggingLevel returns the level set with WithLevel, or the level configured
+// for the logger in the environment.
+func (o options) loggingLevel(id string) zerolog.Level {
+	if o.level != nil {
+		return *o.level
+	}
+	return getLoggingLevel(id)
+}
+
+// includeTimestamp returns whether entries carry a timestamp, def unless set
+// with WithTimestamp.
+func (o options) includeTimestamp(def bool) bool {
+	if o.timestamp != nil {
+		return *o.timestamp
+	}
+	return def
+}
+
+// With binds key/value pairs to every entry of the logger. Bound fields are
+// checked against the key policy together with the fields of each call.
 func With(args ...interface{}) Option {
-	return func(ctx zerolog.Context) zerolog.Context {
-		l := ctx.Logger()
-		l.UpdateContext(func(c zerolog.Context) zerolog.Context {
-			return c.Fields(args)
-		})
-		return l.With()
+	return func(o *options) {
+		o.fields = append(o.fields, args...)
 	}
 }
+
+// defaultCallerSkipFrames is the skip frame count of WithCaller that reports
+// the direct caller of a Logger method, as with zerolog's
+// CallerWithSkipFrameCount in earlier versions.
+const defaultCallerSkipFrames = 3
+
+// WithCaller reports the caller of every logging call in the "caller" field.
+// The optional skipFrameCount keeps its zerolog meaning: 3, the default,
+// reports the direct caller of the Logger method and every additional frame
+// moves one further up the stack, for loggers that are always called through
+// a helper of their own. Smaller counts report the direct caller as well.
 func WithCaller(skipFrameCount ...int) Option {
-	zerolog.CallerMarshalFunc = func(pc uintptr, file string, line int) string {
-		return fmt.Sprintf("%s:%d", filepath.Base(file), line)
-	}
-	return func(ctx zerolog.Context) zerolog.Context {
+	return func(o *options) {
+		if o.caller == CallerOff {
+			o.caller = CallerShort
+		}
 		if len(skipFrameCount) > 0 {
-			ctx = ctx.CallerWithSkipFrameCount(skipFrameCount[0])
-		} else {
-			ctx = ctx.CallerWithSkipFrameCount(3)
+			o.callerSkip = max(skipFrameCount[0]-defaultCallerSkipFrames, 0)
 		}
-		return ctx
 	}
 }
+func WithCallerFormat(format CallerFormat) Option {
+	return func(o *options) {
+		o.caller = format
+	}
+}
+
+// WithHook runs hook for every entry. Fields the hook adds are appended to the
+// entry, and an entry the hook discards is not written.
 func WithHook(hook zerolog.Hook) Option {
-	return func(ctx zerolog.Context) zerolog.Context {
-		return ctx.Logger().Hook(hook).With()
+	return func(o *options) {
+		if hook != nil {
+			o.hooks = append(o.hooks, hook)
+		}
 	}
 }
 func WithLevel(level zerolog.Level) Option {
-	return func(ctx zerolog.Context) zerolog.Context {
-		return ctx.Logger().Level(level).With()
+	return func(o *options) {
+		o.level = &level
 	}
 }
 func WithLevelStr(level string) Option {
@@ -45,7 +105,104 @@ func WithLevelStr(level string) Option {
 	if err != nil {
 		lvl = zerolog.InfoLevel
 	}
-	return func(ctx zerolog.Context) zerolog.Context {
-		return ctx.Logger().Level(lvl).With()
+	return WithLevel(lvl)
+}
+
+// WithTimestamp turns the timestamp of entries on or off.
+func WithTimestamp(enabled bool) Option {
+	return func(o *options) {
+		o.timestamp = &enabled
 	}
 }
+
+// WithTimeFormat sets the time.Format layout of the timestamp.
+func WithTimeFormat(layout string) Option {
+	return func(o *options) {
+		o.timeFormat = layout
+	}
+}
+
+// WithFormat sets the layout of the lines written by the text backends (file
+// and custom loggers). The template may refer to {time}, {level}, {logger},
+// {message} and {fields}; anything else is copied as is.
+func WithFormat(template string) Option {
+	return func(o *options) {
+		o.format = template
+	}
+}
+
+// region - hooks
+
+// runHooks runs hooks for an entry of a backend other than zerolog and
+// returns args with the fields they added, checked against the key policy
+// like the fields of the call. The result is false if a hook discarded the
+// entry.
+func runHooks(hooks []zerolog.Hook, level zerolog.Level, message string, args []interface{}, reserved func(string) bool) ([]interface{}, bool) {
+	if len(hooks) == 0 {
+		return args, true
+	}
+	var buf bytes.Buffer
+	// the event has no level of its own, so neither the level of the logger
+	// nor zerolog's global level filter it
+	lg := zerolog.New(&buf)
+	e := lg.Log()
+	if e == nil {
+		return args, true
+	}
+	for _, hook := range hooks {
+		hook.Run(e, level, message)
+	}
+	e.Send()
+	if buf.Len() == 0 {
+		return nil, false
+	}
+	extra := decodeHookFields(buf.Bytes())
+	if len(extra) == 0 {
+		return args, true
+	}
+	return sanitizeKeys(append(args[:len(args):len(args)], extra...), reserved), true
+}
+
+// decodeHookFields decodes the fields of a zerolog entry in their order.
+// Numbers decode to int64 where they fit and to float64 otherwise.
+func decodeHookFields(data []byte) []interface{} {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil
+	}
+	var fields []interface{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fields
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return fields
+		}
+		fields = append(fields, key, jsonValue(value))
+	}
+	return fields
+}
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return value
+}
+
+// endregion
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomLoggerOptions(t *testing.T) {
+	var out string
+	l := GetCustomLogger("options-custom", func(msg string) { out = msg },
+		WithLevel(zerolog.WarnLevel),
+		With("service", "api"),
+		WithTimestamp(true),
+		WithTimeFormat("15:04"),
+		WithFormat("{time}|{level}|{logger}|{message}|{fields}|{other}"),
+	)
+	defer DeleteCustomLogger("options-custom")
+	l.Info("skipped")
+	if out != "" {
+		t.Fatalf("expected info to be filtered out: %q", out)
+	}
+	l.Warn("hello", "a", 1)
+	parts := strings.Split(out, "|")
+	if len(parts) != 6 || len(parts[0]) != 5 || parts[1] != "WRN" || parts[2] != "options-custom" || parts[3] != "hello" || parts[5] != "{other}" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if !strings.Contains(parts[4], "service=api") || !strings.Contains(parts[4], "a=1") {
+		t.Fatalf("unexpected fields: %q", parts[4])
+	}
+}
+
+func TestFileLoggerOptions(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "options.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := GetFileLogger(f, "options-file", WithTimestamp(false), With("service", "api"))
+	defer DeleteFileLogger("options-file")
+	l.Info("hello")
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "INF [options-file] hello  service=api") {
+		t.Fatalf("unexpected output: %q", data)
+	}
+	l.Clone("options-clone").Info("cloned")
+	data, err = os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\nINF [options-clone] cloned  service=api") {
+		t.Fatalf("expected the clone to keep the options: %q", data)
+	}
+}
+
+func TestHookOptions(t *testing.T) {
+	var out string
+	l := GetCustomLogger("options-hook", func(msg string) { out = msg },
+		WithHook(zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, message string) {
+			if message == "drop" {
+				e.Discard()
+				return
+			}
+			e.Str("hooked", level.String()).Int("n", 3).Str("level", "hook")
+		})),
+	)
+	defer DeleteCustomLogger("options-hook")
+	l.Info("keep")
+	if !strings.HasSuffix(out, " hooked=info n=3 fields.level=hook") {
+		t.Fatalf("expected the hook fields: %q", out)
+	}
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	out = ""
+	l.Info("filtered globally")
+	if !strings.Contains(out, "hooked=info") {
+		t.Fatalf("expected zerolog's global level to be ignored: %q", out)
+	}
+	out = ""
+	l.Info("drop")
+	if out != "" {
+		t.Fatalf("expected the entry to be discarded: %q", out)
+	}
+}
+
+func TestSlogLoggerOptions(t *testing.T) {
+	var buf bytes.Buffer
+	l := GetSlogLogger("options-slog", slog.NewTextHandler(&buf, nil), WithTimestamp(false), With("service", "api"))
+	defer DeleteSlogLogger("options-slog")
+	l.Info("hello")
+	if got := buf.String(); got != "level=INFO msg=hello logger=options-slog service=api\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	buf.Reset()
+	l.Clone("options-slog-clone").Info("cloned")
+	if got := buf.String(); got != "level=INFO msg=cloned logger=options-slog-clone service=api\n" {
+		t.Fatalf("expected the clone to keep the options: %q", got)
+	}
+}
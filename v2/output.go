@@ -0,0 +1,748 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// region - outputs
+
+// Entry is a log entry as handed to outputs. Fields holds the key/value pairs
+// of the entry after the bound fields, groups, the caller and hook fields have
+// been applied, with string keys and values copied into plain data (scalars,
+// maps and slices) when the entry was logged.
+type Entry struct {
+	Time    time.Time
+	Level   zerolog.Level
+	Logger  string
+	Message string
+	Fields  []interface{}
+}
+
+// Output receives the entries of the loggers it is attached to with
+// WithOutput, in addition to the own output of their backend. Write must not
+// block for long; outputs that talk to a network queue the entries and send
+// them in the background. Close flushes and releases the output; Shutdown
+// closes all outputs in use.
+type Output interface {
+	Write(e Entry)
+	Close() error
+}
+
+// WithOutput sends every entry of the logger to out as well.
+func WithOutput(out Output) Option {
+	return func(o *options) {
+		if out != nil {
+			o.outputs = append(o.outputs, out)
+			registerOutput(out)
+		}
+	}
+}
+
+var outputs struct {
+	mu  sync.Mutex
+	all []Output
+}
+
+func registerOutput(out Output) {
+	outputs.mu.Lock()
+	defer outputs.mu.Unlock()
+	for _, o := range outputs.all {
+		if o == out {
+			return
+		}
+	}
+	outputs.all = append(outputs.all, out)
+}
+
+func closeOutputs() {
+	outputs.mu.Lock()
+	all := outputs.all
+	outputs.all = nil
+	outputs.mu.Unlock()
+	for _, out := range all {
+		if err := out.Close(); err != nil {
+			reportError(err)
+		}
+	}
+}
+
+func writeOutputs(outputs []Output, t time.Time, level zerolog.Level, logger, message string, args []interface{}) {
+	if len(outputs) == 0 {
+		return
+	}
+	fields := make([]interface{}, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[i] = fmt.Sprint(args[i])
+		fields[i+1] = snapshotValue(args[i+1], 0)
+	}
+	e := Entry{Time: t, Level: level, Logger: logger, Message: message, Fields: fields}
+	for _, out := range outputs {
+		out.Write(e)
+	}
+}
+
+const maxSnapshotDepth = 32
+
+// snapshotValue copies a field value into plain data (scalars, maps and
+// slices) while the entry is logged, so that outputs encoding it later on a
+// goroutine of their own neither race with the caller nor run its methods.
+// Errors and panics keep their stack in an objectSnapshot.
+func snapshotValue(value interface{}, depth int) interface{} {
+	if depth > maxSnapshotDepth {
+		return fmt.Sprint(value)
+	}
+	switch v := value.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, json.Number, time.Time, time.Duration:
+		return value
+	case []byte:
+		return bytes.Clone(v)
+	case errorValue:
+		s := snapshotObject(v, v.stack, depth)
+		s.errorMessage = v.err.Error()
+		s.errorType = fmt.Sprintf("%T", v.err)
+		return s
+	case panicValue:
+		return snapshotObject(v, v.stack, depth)
+	}
+	switch v := plainValue(value).(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = snapshotValue(item, depth+1)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = snapshotValue(item, depth+1)
+		}
+		return items
+	case error, encoding.TextMarshaler:
+		s, _ := textValue(v)
+		return s
+	case json.Marshaler:
+		return snapshotJSON(v)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return snapshotValue(rv.Elem().Interface(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = snapshotValue(rv.Index(i).Interface(), depth+1)
+		}
+		return items
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = snapshotValue(iter.Value().Interface(), depth+1)
+		}
+		return m
+	case reflect.Struct:
+		return snapshotJSON(value)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return fmt.Sprint(value)
+	}
+	return value
+}
+
+// snapshotJSON returns the JSON encoding of value decoded into plain data.
+func snapshotJSON(value interface{}) interface{} {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var result interface{}
+	if err := dec.Decode(&result); err != nil {
+		return string(b)
+	}
+	return jsonValue(result)
+}
+
+func snapshotObject(value ObjectMarshaler, stack stackFrames, depth int) objectSnapshot {
+	fields, _ := snapshotValue(plainValue(value), depth+1).(map[string]interface{})
+	return objectSnapshot{fields: fields, stack: stack}
+}
+
+// objectSnapshot is an error or panic field as captured by writeOutputs: its
+// plain fields, its stack and, for errors, the message and type.
+type objectSnapshot struct {
+	fields       map[string]interface{}
+	stack        stackFrames
+	errorMessage string
+	errorType    string
+}
+
+func (s objectSnapshot) MarshalLogObject(enc ObjectEncoder) {
+	for _, key := range sortedKeys(s.fields) {
+		enc.Add(key, s.fields[key])
+	}
+}
+func (s objectSnapshot) stackTrace() stackFrames {
+	return s.stack
+}
+
+// field returns the value of the first field with the key.
+func (e Entry) field(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if fmt.Sprint(e.Fields[i]) == key {
+			return e.Fields[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// flattenFields returns the fields with objects and arrays expanded into
+// dotted keys, for outputs that only take scalar values.
+func flattenFields(args []interface{}) []interface{} {
+	var result []interface{}
+	for i := 0; i+1 < len(args); i += 2 {
+		result = appendFlat(result, fmt.Sprint(args[i]), plainValue(args[i+1]))
+	}
+	return result
+}
+
+func appendFlat(result []interface{}, key string, value interface{}) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			result = appendFlat(result, key+"."+k, plainValue(v[k]))
+		}
+		return result
+	case []interface{}:
+		for i, item := range v {
+			result = appendFlat(result, key+"."+strconv.Itoa(i), plainValue(item))
+		}
+		return result
+	}
+	return append(result, key, value)
+}
+
+// splitCaller splits a caller in file:line format.
+func splitCaller(c string) (string, string, bool) {
+	i := strings.LastIndexByte(c, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(c[i+1:]); err != nil {
+		return "", "", false
+	}
+	return c[:i], c[i+1:], true
+}
+
+// stringValue returns the text of a scalar field value.
+func stringValue(value interface{}) string {
+	if s, ok := textValue(value); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// endregion
+
+// region - output options
+
+const (
+	defaultOutputBatchSize     = 100
+	defaultOutputFlushInterval = time.Second
+	defaultOutputQueueSize     = 10000
+	defaultOutputRetries       = 3
+	defaultOutputBackoff       = 500 * time.Millisecond
+	maxOutputBackoff           = 30 * time.Second
+	outputCloseTimeout         = 10 * time.Second
+	maxOutputResponse          = 16 << 20
+)
+
+// OutputOption configures the outputs that send entries over the network.
+type OutputOption func(*outputOptions)
+
+type outputOptions struct {
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	retries       int
+	backoff       time.Duration
+	gzip          bool
+	headers       http.Header
+	client        *http.Client
+	tls           *tls.Config
+	resource      []interface{}
+	syslog        syslogOptions
+	gelf          gelfOptions
+	loki          lokiOptions
+	elasticsearch elasticsearchOptions
+	fluent        fluentOptions
+}
+
+func newOutputOptions(opts []OutputOption) outputOptions {
+	o := outputOptions{
+		batchSize:     defaultOutputBatchSize,
+		flushInterval: defaultOutputFlushInterval,
+		queueSize:     defaultOutputQueueSize,
+		retries:       defaultOutputRetries,
+		backoff:       defaultOutputBackoff,
+		headers:       make(http.Header),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		syslog:        syslogOptions{facility: FacilityUser},
+		gelf:          gelfOptions{chunkSize: defaultGELFChunkSize},
+		loki:          lokiOptions{labels: []string{"logger", "level", "service"}, maxLabelValues: defaultLokiMaxLabelValues},
+		elasticsearch: elasticsearchOptions{index: defaultElasticsearchIndex},
+		fluent:        fluentOptions{ackTimeout: defaultFluentAckTimeout},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// OutputBatchSize sets the number of entries sent at once.
+func OutputBatchSize(n int) OutputOption {
+	return func(o *outputOptions) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// OutputFlushInterval sets how long entries wait for a batch to fill up.
+func OutputFlushInterval(d time.Duration) OutputOption {
+	return func(o *outputOptions) {
+		if d > 0 {
+			o.flushInterval = d
+		}
+	}
+}
+
+// OutputQueueSize bounds the number of queued entries; entries logged while
+// the queue is full are dropped and reported to the error handler.
+func OutputQueueSize(n int) OutputOption {
+	return func(o *outputOptions) {
+		if n > 0 {
+			o.queueSize = n
+		}
+	}
+}
+
+// OutputRetries retries a failed batch up to n times, waiting backoff before
+// the first retry and twice as long before each further one.
+func OutputRetries(n int, backoff time.Duration) OutputOption {
+	return func(o *outputOptions) {
+		o.retries = n
+		o.backoff = backoff
+	}
+}
+
+// OutputGzip compresses the requests of HTTP outputs.
+func OutputGzip() OutputOption {
+	return func(o *outputOptions) {
+		o.gzip = true
+	}
+}
+
+// OutputHeader adds a header to the requests of HTTP outputs, e.g. for
+// authentication.
+func OutputHeader(key, value string) OutputOption {
+	return func(o *outputOptions) {
+		o.headers.Add(key, value)
+	}
+}
+
+// OutputBasicAuth authenticates the requests of HTTP outputs with basic
+// authentication.
+func OutputBasicAuth(username, password string) OutputOption {
+	return func(o *outputOptions) {
+		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		o.headers.Set("Authorization", "Basic "+auth)
+	}
+}
+
+// OutputHTTPClient replaces the client of HTTP outputs.
+func OutputHTTPClient(c *http.Client) OutputOption {
+	return func(o *outputOptions) {
+		if c != nil {
+			o.client = c
+		}
+	}
+}
+
+// OutputTLSConfig sets the TLS configuration of outputs that connect over
+// TLS.
+func OutputTLSConfig(cfg *tls.Config) OutputOption {
+	return func(o *outputOptions) {
+		o.tls = cfg
+	}
+}
+
+// OutputResource adds key/value pairs describing the process, such as
+// service.name, to the entries. service.name and host.name default to the
+// OTEL_SERVICE_NAME environment variable (or the name of the executable) and
+// the host name.
+func OutputResource(args ...interface{}) OutputOption {
+	return func(o *outputOptions) {
+		o.resource = append(o.resource, args...)
+	}
+}
+
+// resourceAttributes returns the resource with the defaults in front of the
+// attributes without a value of their own.
+func (o outputOptions) resourceAttributes() []interface{} {
+	var result []interface{}
+	keys := make(map[string]bool)
+	for i := 0; i+1 < len(o.resource); i += 2 {
+		keys[fmt.Sprint(o.resource[i])] = true
+	}
+	if !keys["service.name"] {
+		result = append(result, "service.name", serviceName())
+	}
+	if !keys["host.name"] {
+		result = append(result, "host.name", hostName())
+	}
+	return append(result, o.resource...)
+}
+
+func serviceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+func hostName() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// permanentError marks errors that retrying does not fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// partialError reports that only some entries of a batch failed; only those
+// are retried.
+type partialError struct {
+	retry []Entry
+	err   error
+}
+
+func (e partialError) Error() string {
+	return e.err.Error()
+}
+func (e partialError) Unwrap() error {
+	return e.err
+}
+
+// post sends body to url and classifies the response: 429 and 5xx are worth
+// a retry, other non-2xx responses are not.
+func (o outputOptions) post(url, contentType string, body []byte) error {
+	_, err := o.postResponse(url, contentType, body)
+	return err
+}
+
+// postResponse is post returning the body of a successful response.
+func (o outputOptions) postResponse(url, contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader = bytes.NewReader(body)
+	if o.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		reader = &buf
+	}
+	r, err := http.NewRequest(http.MethodPost, url, reader)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	for key, values := range o.headers {
+		r.Header[key] = values
+	}
+	r.Header.Set("Content-Type", contentType)
+	if o.gzip {
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := o.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return io.ReadAll(io.LimitReader(resp.Body, maxOutputResponse))
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	err = fmt.Errorf("%s: %s %s", url, resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, err
+	}
+	return nil, permanentError{err}
+}
+
+// endregion
+
+// region - connections
+
+const (
+	outputDialTimeout  = 10 * time.Second
+	outputWriteTimeout = 10 * time.Second
+)
+
+// outputConn is a connection of a stream or datagram output that is dialled
+// on first use and redialled once when a write fails.
+type outputConn struct {
+	dial func() (net.Conn, error)
+	conn net.Conn
+}
+
+func (c *outputConn) write(b []byte) error {
+	for attempt := 0; ; attempt++ {
+		if c.conn == nil {
+			conn, err := c.dial()
+			if err != nil {
+				return err
+			}
+			c.conn = conn
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(outputWriteTimeout))
+		_, err := c.conn.Write(b)
+		if err == nil {
+			return nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (c *outputConn) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// dialOutput dials address; the "tls" network is TCP with TLS.
+func dialOutput(network, address string, cfg *tls.Config) (net.Conn, error) {
+	d := &net.Dialer{Timeout: outputDialTimeout}
+	if network == "tls" {
+		return tls.DialWithDialer(d, "tcp", address, cfg)
+	}
+	return d.Dial(network, address)
+}
+
+// endregion
+
+// region - batching
+
+// batcher queues entries and hands them to send in batches from a goroutine
+// of its own, retrying failed batches until it is closed.
+type batcher struct {
+	name    string
+	o       outputOptions
+	send    func(batch []Entry) error
+	queue   chan Entry
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+func newBatcher(name string, o outputOptions, send func(batch []Entry) error) *batcher {
+	b := &batcher{
+		name:    name,
+		o:       o,
+		send:    send,
+		queue:   make(chan Entry, o.queueSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher) add(e Entry) {
+	if b.closed.Load() {
+		return
+	}
+	select {
+	case b.queue <- e:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// flush sends the queued entries and waits until they are sent, at most
+// outputCloseTimeout.
+func (b *batcher) flush() {
+	if b.closed.Load() {
+		return
+	}
+	timer := time.NewTimer(outputCloseTimeout)
+	defer timer.Stop()
+	ack := make(chan struct{})
+	select {
+	case b.flushes <- ack:
+	case <-b.done:
+		return
+	case <-timer.C:
+		reportError(fmt.Errorf("%s: flush timed out", b.name))
+		return
+	}
+	select {
+	case <-ack:
+	case <-b.done:
+	case <-timer.C:
+		reportError(fmt.Errorf("%s: flush timed out", b.name))
+	}
+}
+
+// close sends the queued entries without retrying failed batches and waits
+// until they are sent, at most outputCloseTimeout.
+func (b *batcher) close() {
+	b.once.Do(func() {
+		b.closed.Store(true)
+		close(b.stop)
+	})
+	timer := time.NewTimer(outputCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-b.done:
+	case <-timer.C:
+		reportError(fmt.Errorf("%s: close timed out", b.name))
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.o.flushInterval)
+	defer ticker.Stop()
+	batch := make([]Entry, 0, b.o.batchSize)
+	deliver := func() {
+		b.deliver(batch)
+		batch = make([]Entry, 0, b.o.batchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-b.queue:
+				batch = append(batch, e)
+				if len(batch) >= b.o.batchSize {
+					deliver()
+				}
+			default:
+				deliver()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case e := <-b.queue:
+			batch = append(batch, e)
+			if len(batch) >= b.o.batchSize {
+				deliver()
+			}
+		case <-ticker.C:
+			deliver()
+		case ack := <-b.flushes:
+			drain()
+			close(ack)
+		case <-b.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (b *batcher) deliver(batch []Entry) {
+	if n := b.dropped.Swap(0); n > 0 {
+		reportError(fmt.Errorf("%s: queue full, dropped %d entries", b.name, n))
+	}
+	if len(batch) == 0 {
+		return
+	}
+	backoff := b.o.backoff
+	for attempt := 0; ; attempt++ {
+		err := b.send(batch)
+		if err == nil {
+			return
+		}
+		var partial partialError
+		if errors.As(err, &partial) {
+			batch = partial.retry
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= b.o.retries || b.closed.Load() {
+			reportError(fmt.Errorf("%s: dropped %d entries: %w", b.name, len(batch), err))
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-b.stop:
+			timer.Stop()
+		}
+		backoff *= 2
+		if backoff > maxOutputBackoff {
+			backoff = maxOutputBackoff
+		}
+	}
+}
+
+// endregion
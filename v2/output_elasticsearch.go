@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// region - elasticsearch
+
+const (
+	defaultElasticsearchIndex = "logs-{2006.01.02}"
+	ecsVersion                = "8.11.0"
+)
+
+type elasticsearchOptions struct {
+	index string
+}
+
+// ElasticsearchIndex sets the pattern of the index names. Text in braces is a
+// time.Format layout applied to the UTC time of the entry, so the default
+// "logs-{2006.01.02}" writes to an index per day.
+func ElasticsearchIndex(pattern string) OutputOption {
+	return func(o *outputOptions) {
+		if pattern != "" {
+			o.elasticsearch.index = pattern
+		}
+	}
+}
+
+// ElasticsearchAPIKey authenticates the requests with an API key, given in
+// its encoded form.
+func ElasticsearchAPIKey(key string) OutputOption {
+	return func(o *outputOptions) {
+		o.headers.Set("Authorization", "ApiKey "+key)
+	}
+}
+
+// ElasticsearchOutput indexes entries in Elasticsearch or OpenSearch with the
+// bulk API.
+type ElasticsearchOutput struct {
+	endpoint string
+	options  outputOptions
+	resource []interface{}
+	batcher  *batcher
+}
+
+// NewElasticsearchOutput returns an output indexing into the cluster at
+// endpoint, e.g. http://localhost:9200. Entries are mapped to the Elastic
+// Common Schema: @timestamp, message, log.level, log.logger, log.origin.*
+// from the caller, error.* from the first error, trace.id and span.id, and
+// the resource attributes such as service.name. Other fields keep their keys.
+// Documents the cluster rejects with 429 or 5xx are retried on their own;
+// other rejections are reported to the error handler. Use OutputBasicAuth or
+// ElasticsearchAPIKey to authenticate.
+func NewElasticsearchOutput(endpoint string, opts ...OutputOption) (*ElasticsearchOutput, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Elasticsearch endpoint %q", endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/_bulk"
+	o := &ElasticsearchOutput{endpoint: u.String(), options: newOutputOptions(opts)}
+	o.resource = o.options.resourceAttributes()
+	o.batcher = newBatcher("elasticsearch", o.options, o.send)
+	return o, nil
+}
+
+func (o *ElasticsearchOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *ElasticsearchOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *ElasticsearchOutput) Close() error {
+	o.batcher.close()
+	return nil
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool                                     `json:"errors"`
+	Items  []map[string]elasticsearchBulkItemResult `json:"items"`
+}
+type elasticsearchBulkItemResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+func (o *ElasticsearchOutput) send(batch []Entry) error {
+	var body bytes.Buffer
+	for _, e := range batch {
+		action, _ := json.Marshal(map[string]interface{}{"create": map[string]string{"_index": o.index(e.Time)}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(o.document(e))
+		body.WriteByte('\n')
+	}
+	data, err := o.options.postResponse(o.endpoint, "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	// the request was accepted, so a batch with an unknown result is reported
+	// rather than retried, which could index its documents twice
+	var resp elasticsearchBulkResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		reportError(fmt.Errorf("elasticsearch: unknown result of %d documents: invalid bulk response: %w", len(batch), err))
+		return nil
+	}
+	if len(resp.Items) != len(batch) {
+		reportError(fmt.Errorf("elasticsearch: unknown result of %d documents: bulk response has %d items", len(batch), len(resp.Items)))
+		return nil
+	}
+	if !resp.Errors {
+		return nil
+	}
+	var retry []Entry
+	var retryErr, dropErr json.RawMessage
+	dropped := 0
+	for i, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == 429 || result.Status >= 500:
+				retry = append(retry, batch[i])
+				retryErr = result.Error
+			case result.Status >= 300:
+				dropped++
+				dropErr = result.Error
+			}
+		}
+	}
+	if dropped > 0 {
+		reportError(fmt.Errorf("elasticsearch: dropped %d rejected documents: %s", dropped, dropErr))
+	}
+	if len(retry) > 0 {
+		return partialError{retry: retry, err: fmt.Errorf("%d documents failed: %s", len(retry), retryErr)}
+	}
+	return nil
+}
+
+// index returns the index name for an entry logged at t.
+func (o *ElasticsearchOutput) index(t time.Time) string {
+	pattern := o.options.elasticsearch.index
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(pattern, '{')
+		end := strings.IndexByte(pattern, '}')
+		if start < 0 || end < start {
+			sb.WriteString(pattern)
+			return sb.String()
+		}
+		sb.WriteString(pattern[:start])
+		sb.WriteString(t.UTC().Format(pattern[start+1 : end]))
+		pattern = pattern[end+1:]
+	}
+}
+
+// document returns e as an ECS document. Fields with a key already in the
+// document are left out, as Elasticsearch rejects duplicate keys.
+func (o *ElasticsearchOutput) document(e Entry) []byte {
+	var b bytes.Buffer
+	keys := make(map[string]bool)
+	add := func(key string, value interface{}) {
+		if keys[key] {
+			return
+		}
+		if len(keys) > 0 {
+			b.WriteByte(',')
+		}
+		keys[key] = true
+		appendJSONField(&b, key, value)
+	}
+	b.WriteByte('{')
+	add("@timestamp", e.Time.UTC().Format(time.RFC3339Nano))
+	add("message", e.Message)
+	add("log.level", e.Level.String())
+	add("log.logger", e.Logger)
+	add("ecs.version", ecsVersion)
+	for i := 0; i+1 < len(o.resource); i += 2 {
+		add(fmt.Sprint(o.resource[i]), plainValue(o.resource[i+1]))
+	}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		key, value := fmt.Sprint(e.Fields[i]), e.Fields[i+1]
+		switch v := value.(type) {
+		case objectSnapshot:
+			if v.errorType != "" && !keys["error.message"] {
+				add("error.message", v.errorMessage)
+				add("error.type", v.errorType)
+				if len(v.stack) > 0 {
+					var sb strings.Builder
+					v.stack.MarshalLogArray(textStackEncoder{&sb})
+					add("error.stack_trace", strings.TrimPrefix(sb.String(), "\n"))
+				}
+				continue
+			}
+		case string:
+			switch key {
+			case "caller":
+				if file, line, ok := splitCaller(v); ok {
+					add("log.origin.file.name", file)
+					add("log.origin.file.line", json.Number(line))
+				} else {
+					add("log.origin.function", v)
+				}
+				continue
+			case "trace_id":
+				add("trace.id", v)
+				continue
+			case "span_id":
+				add("span.id", v)
+				continue
+			}
+		}
+		add(key, plainValue(value))
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// endregion
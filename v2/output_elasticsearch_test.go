@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticsearchOutput(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if r.URL.Path != "/es/_bulk" || !ok || user != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			lines = append(lines, line)
+		}
+		mu.Lock()
+		requests = append(requests, lines)
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			_, _ = fmt.Fprint(w, `{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},{"create":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"errors":false,"items":[{"create":{"status":201}}]}`)
+	}))
+	defer server.Close()
+
+	defer SetErrorHandler(defaultErrorHandler)
+	var reported []error
+	SetErrorHandler(func(err error) { reported = append(reported, err) })
+	out, err := NewElasticsearchOutput(server.URL+"/es", OutputBasicAuth("elastic", "secret"), OutputRetries(2, time.Millisecond),
+		ElasticsearchIndex("app-{2006.01}"), OutputResource("service.name", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("es", nil, WithOutput(out), WithCallerFormat(CallerShort))
+	defer DeleteCustomLogger("es")
+	l.Error("failed", "error", errors.New("boom"), "user", "bob")
+	l.Info("retried")
+	l.Info("rejected")
+	out.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 || len(requests[0]) != 6 || len(requests[1]) != 2 {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	index := "app-" + time.Now().UTC().Format("2006.01")
+	if action := requests[0][0]["create"].(map[string]interface{}); action["_index"] != index {
+		t.Fatalf("unexpected action: %v", action)
+	}
+	doc := requests[0][1]
+	want := map[string]interface{}{
+		"message": "failed", "log.level": "error", "log.logger": "es", "service.name": "api", "user": "bob",
+		"error.message": "boom", "error.type": "*errors.errorString", "log.origin.file.name": "output_elasticsearch_test.go",
+	}
+	for key, value := range want {
+		if doc[key] != value {
+			t.Errorf("%s = %v, want %v", key, doc[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fmt.Sprint(doc["@timestamp"])); err != nil || doc["log.origin.file.line"] == nil {
+		t.Errorf("unexpected document: %v", doc)
+	}
+	if doc := requests[1][1]; doc["message"] != "retried" {
+		t.Errorf("unexpected retry: %v", doc)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "dropped 1 rejected documents") {
+		t.Errorf("unexpected errors: %v", reported)
+	}
+}
+
+func TestElasticsearchOutputInvalidResponse(t *testing.T) {
+	defer SetErrorHandler(defaultErrorHandler)
+	responses := []string{`<html>`, `{"errors":false,"items":[]}`}
+	for _, response := range responses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, response)
+		}))
+		var mu sync.Mutex
+		var reported []error
+		SetErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+		})
+		out, err := NewElasticsearchOutput(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := GetCustomLogger("es-invalid", nil, WithOutput(out))
+		l.Info("lost")
+		out.Flush()
+		_ = out.Close()
+		DeleteCustomLogger("es-invalid")
+		server.Close()
+
+		mu.Lock()
+		if len(reported) != 1 || !strings.Contains(reported[0].Error(), "unknown result of 1 documents") {
+			t.Errorf("response %s: unexpected errors: %v", response, reported)
+		}
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,249 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+)
+
+// region - fluent
+
+// FluentMode is the event mode of the Fluent forward protocol.
+type FluentMode int
+
+const (
+	// FluentForwardMode sends the events of a tag as an array.
+	FluentForwardMode FluentMode = iota
+	// FluentMessageMode sends every event on its own.
+	FluentMessageMode
+	// FluentPackedForwardMode sends the events of a tag as one binary blob of
+	// concatenated events.
+	FluentPackedForwardMode
+)
+
+const defaultFluentAckTimeout = 10 * time.Second
+
+type fluentOptions struct {
+	mode       FluentMode
+	tagPrefix  string
+	ack        bool
+	ackTimeout time.Duration
+}
+
+// FluentEventMode sets the event mode, forward mode by default.
+func FluentEventMode(mode FluentMode) OutputOption {
+	return func(o *outputOptions) {
+		o.fluent.mode = mode
+	}
+}
+
+// FluentTagPrefix puts prefix and a dot in front of the logger id to make the
+// tag of the events.
+func FluentTagPrefix(prefix string) OutputOption {
+	return func(o *outputOptions) {
+		o.fluent.tagPrefix = prefix
+	}
+}
+
+// FluentRequireAck asks the server to acknowledge every chunk and resends
+// the chunks that are not acknowledged within timeout.
+func FluentRequireAck(timeout time.Duration) OutputOption {
+	return func(o *outputOptions) {
+		o.fluent.ack = true
+		if timeout > 0 {
+			o.fluent.ackTimeout = timeout
+		}
+	}
+}
+
+// FluentOutput sends entries to Fluentd or Fluent Bit with the forward
+// protocol.
+type FluentOutput struct {
+	options outputOptions
+	conn    *outputConn
+	batcher *batcher
+}
+
+// NewFluentOutput returns an output sending to the forward input at address
+// over the network "tcp", "tls" or "unix". TCP addresses without a port get
+// 24224. The tag of an event is the logger id; the record holds the message,
+// the level and the fields.
+func NewFluentOutput(network, address string, opts ...OutputOption) (*FluentOutput, error) {
+	switch network {
+	case "tcp", "tls":
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, "24224")
+		}
+	case "unix":
+	default:
+		return nil, fmt.Errorf("unsupported Fluent network %q", network)
+	}
+	o := &FluentOutput{options: newOutputOptions(opts)}
+	o.conn = &outputConn{dial: func() (net.Conn, error) {
+		return dialOutput(network, address, o.options.tls)
+	}}
+	o.batcher = newBatcher("fluent", o.options, o.send)
+	return o, nil
+}
+
+func (o *FluentOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *FluentOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *FluentOutput) Close() error {
+	o.batcher.close()
+	return o.conn.close()
+}
+
+// send writes the batch as chunks of one tag each, or of one entry each in
+// message mode.
+func (o *FluentOutput) send(batch []Entry) error {
+	var chunks [][]Entry
+	if o.options.fluent.mode == FluentMessageMode {
+		for i := range batch {
+			chunks = append(chunks, batch[i:i+1])
+		}
+	} else {
+		index := make(map[string]int)
+		for _, e := range batch {
+			tag := o.tag(e)
+			i, ok := index[tag]
+			if !ok {
+				i = len(chunks)
+				index[tag] = i
+				chunks = append(chunks, nil)
+			}
+			chunks[i] = append(chunks[i], e)
+		}
+	}
+	for i, chunk := range chunks {
+		if err := o.sendChunk(chunk); err != nil {
+			var retry []Entry
+			for _, c := range chunks[i:] {
+				retry = append(retry, c...)
+			}
+			return partialError{retry: retry, err: err}
+		}
+	}
+	return nil
+}
+
+func (o *FluentOutput) sendChunk(chunk []Entry) error {
+	var enc msgpackEncoder
+	var id string
+	options := 0
+	if o.options.fluent.mode != FluentMessageMode {
+		options++
+	}
+	if o.options.fluent.ack {
+		var b [16]byte
+		_, _ = rand.Read(b[:])
+		id = base64.StdEncoding.EncodeToString(b[:])
+		options++
+	}
+	n := 2
+	if options > 0 {
+		n = 3
+	}
+	if o.options.fluent.mode == FluentMessageMode {
+		n++
+	}
+	enc.arrayHeader(n)
+	enc.string(o.tag(chunk[0]))
+	switch o.options.fluent.mode {
+	case FluentMessageMode:
+		enc.eventTime(chunk[0].Time)
+		o.record(&enc, chunk[0])
+	case FluentPackedForwardMode:
+		var entries msgpackEncoder
+		for _, e := range chunk {
+			entries.arrayHeader(2)
+			entries.eventTime(e.Time)
+			o.record(&entries, e)
+		}
+		enc.bytes(entries.buf)
+	default:
+		enc.arrayHeader(len(chunk))
+		for _, e := range chunk {
+			enc.arrayHeader(2)
+			enc.eventTime(e.Time)
+			o.record(&enc, e)
+		}
+	}
+	if options > 0 {
+		enc.mapHeader(options)
+		if o.options.fluent.mode != FluentMessageMode {
+			enc.string("size")
+			enc.uint(uint64(len(chunk)))
+		}
+		if id != "" {
+			enc.string("chunk")
+			enc.string(id)
+		}
+	}
+	if err := o.conn.write(enc.buf); err != nil {
+		return err
+	}
+	if id == "" {
+		return nil
+	}
+	return o.readAck(id)
+}
+
+// readAck waits for the acknowledgement of chunk id; the connection is
+// dropped if it does not arrive.
+func (o *FluentOutput) readAck(id string) error {
+	conn := o.conn.conn
+	_ = conn.SetReadDeadline(time.Now().Add(o.options.fluent.ackTimeout))
+	ack, err := decodeMsgpackAck(conn)
+	if err == nil {
+		if ack == id {
+			return nil
+		}
+		err = fmt.Errorf("unexpected acknowledgement %q", ack)
+	}
+	_ = o.conn.close()
+	return err
+}
+
+func (o *FluentOutput) tag(e Entry) string {
+	switch {
+	case o.options.fluent.tagPrefix == "":
+		return e.Logger
+	case e.Logger == "":
+		return o.options.fluent.tagPrefix
+	}
+	return o.options.fluent.tagPrefix + "." + e.Logger
+}
+
+// record appends the record of e. Fields with the key of an earlier field
+// are left out.
+func (o *FluentOutput) record(enc *msgpackEncoder, e Entry) {
+	keys := map[string]bool{"message": true, "level": true}
+	var fields []interface{}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		key := fmt.Sprint(e.Fields[i])
+		if !keys[key] {
+			keys[key] = true
+			fields = append(fields, key, e.Fields[i+1])
+		}
+	}
+	enc.mapHeader(2 + len(fields)/2)
+	enc.string("message")
+	enc.string(e.Message)
+	enc.string("level")
+	enc.string(e.Level.String())
+	for i := 0; i+1 < len(fields); i += 2 {
+		enc.string(fields[i].(string))
+		enc.value(fields[i+1])
+	}
+}
+
+// endregion
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fluentServer accepts one connection and passes every message it reads to
+// messages, acknowledging chunks that ask for it.
+func fluentServer(t *testing.T, ln net.Listener) <-chan []interface{} {
+	messages := make(chan []interface{}, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := decodeMsgpack(r)
+			if err != nil {
+				return
+			}
+			arr, _ := msg.([]interface{})
+			messages <- arr
+			if options, ok := arr[len(arr)-1].(map[string]interface{}); ok && options["chunk"] != nil {
+				var enc msgpackEncoder
+				enc.mapHeader(1)
+				enc.string("ack")
+				enc.string(options["chunk"].(string))
+				_, _ = conn.Write(enc.buf)
+			}
+		}
+	}()
+	return messages
+}
+
+func receive(t *testing.T, messages <-chan []interface{}) []interface{} {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return nil
+}
+
+func TestFluentOutputForward(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := fluentServer(t, ln)
+	out, err := NewFluentOutput("tcp", ln.Addr().String(), FluentTagPrefix("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("fluent", nil, WithOutput(out))
+	defer DeleteCustomLogger("fluent")
+	l.Info("one", "n", 1)
+	l.Warning("two", "tags", []string{"a", "b"})
+	out.Flush()
+
+	msg := receive(t, messages)
+	if len(msg) != 3 || msg[0] != "app.fluent" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+	entries := msg[1].([]interface{})
+	if len(entries) != 2 || msg[2].(map[string]interface{})["size"] != int64(2) {
+		t.Fatalf("unexpected entries: %v", msg)
+	}
+	first := entries[0].([]interface{})
+	if _, ok := first[0].(time.Time); !ok {
+		t.Fatalf("unexpected event time: %v", first[0])
+	}
+	record := first[1].(map[string]interface{})
+	if record["message"] != "one" || record["level"] != "info" || record["n"] != int64(1) {
+		t.Fatalf("unexpected record: %v", record)
+	}
+	record = entries[1].([]interface{})[1].(map[string]interface{})
+	if tags := record["tags"].([]interface{}); len(tags) != 2 || tags[1] != "b" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+}
+
+func TestFluentOutputPackedForwardAck(t *testing.T) {
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "fluent.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := fluentServer(t, ln)
+	out, err := NewFluentOutput("unix", ln.Addr().String(), FluentEventMode(FluentPackedForwardMode), FluentRequireAck(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("fluent-packed", nil, WithOutput(out))
+	defer DeleteCustomLogger("fluent-packed")
+	l.Error("failed")
+	out.Flush()
+
+	msg := receive(t, messages)
+	options := msg[2].(map[string]interface{})
+	if msg[0] != "fluent-packed" || options["chunk"] == nil || options["size"] != int64(1) {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+	entry, err := decodeMsgpack(bytes.NewReader(msg[1].([]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record := entry.([]interface{})[1].(map[string]interface{}); record["message"] != "failed" || record["level"] != "error" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+}
+
+func TestFluentOutputMessageMode(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := fluentServer(t, ln)
+	out, err := NewFluentOutput("tcp", ln.Addr().String(), FluentEventMode(FluentMessageMode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("fluent-message", nil, WithOutput(out))
+	defer DeleteCustomLogger("fluent-message")
+	l.Info("one")
+	l.Info("two")
+	out.Flush()
+	for _, want := range []string{"one", "two"} {
+		msg := receive(t, messages)
+		if len(msg) != 3 || msg[0] != "fluent-message" || msg[2].(map[string]interface{})["message"] != want {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// region - gelf
+
+const (
+	defaultGELFChunkSize = 1420
+	maxGELFChunks        = 128
+)
+
+type gelfOptions struct {
+	chunkSize int
+}
+
+// GELFChunkSize sets the maximum size of the UDP datagrams; larger messages
+// are split into GELF chunks. The default of 1420 bytes suits most networks,
+// 8154 is common within a LAN.
+func GELFChunkSize(n int) OutputOption {
+	return func(o *outputOptions) {
+		if n > 12 {
+			o.gelf.chunkSize = n
+		}
+	}
+}
+
+// GELFOutput sends entries to Graylog in the Graylog Extended Log Format.
+type GELFOutput struct {
+	network  string
+	address  string
+	options  outputOptions
+	hostname string
+	conn     *outputConn
+	batcher  *batcher
+}
+
+// NewGELFOutput returns an output sending GELF messages over the network
+// "udp" (gzip compressed and chunked), "tcp" or "tls" (null byte framed), or
+// "http" and "https", where address is the URL of the input; /gelf is
+// appended to URLs without a path. Addresses without a port get 12201.
+// Fields become additional fields with a "_" prefix.
+func NewGELFOutput(network, address string, opts ...OutputOption) (*GELFOutput, error) {
+	o := &GELFOutput{network: network, options: newOutputOptions(opts), hostname: hostName()}
+	switch network {
+	case "udp", "tcp", "tls":
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, "12201")
+		}
+		o.conn = &outputConn{dial: func() (net.Conn, error) {
+			return dialOutput(network, address, o.options.tls)
+		}}
+	case "http", "https":
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid GELF address %q", address)
+		}
+		if u.Scheme == "" {
+			u.Scheme = network
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/gelf"
+		}
+		address = u.String()
+	default:
+		return nil, fmt.Errorf("unsupported GELF network %q", network)
+	}
+	o.address = address
+	o.batcher = newBatcher("gelf", o.options, o.send)
+	return o, nil
+}
+
+func (o *GELFOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *GELFOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *GELFOutput) Close() error {
+	o.batcher.close()
+	if o.conn != nil {
+		return o.conn.close()
+	}
+	return nil
+}
+
+// send sends the entries one message at a time. An entry that cannot be
+// encoded or is rejected is reported and skipped; a transport error returns
+// the entries from the failed one on for a retry.
+func (o *GELFOutput) send(batch []Entry) error {
+	for i, e := range batch {
+		msg, err := json.Marshal(o.message(e))
+		if err != nil {
+			err = permanentError{err}
+		} else {
+			err = o.sendMessage(msg)
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			reportError(fmt.Errorf("gelf: dropped entry %q: %w", e.Message, err))
+			continue
+		}
+		if err != nil {
+			return partialError{retry: batch[i:], err: err}
+		}
+	}
+	return nil
+}
+
+func (o *GELFOutput) sendMessage(msg []byte) error {
+	switch o.network {
+	case "udp":
+		chunks, err := o.chunks(msg)
+		if err != nil {
+			return permanentError{err}
+		}
+		for _, chunk := range chunks {
+			if err := o.conn.write(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "tcp", "tls":
+		return o.conn.write(append(msg, 0))
+	}
+	return o.options.post(o.address, "application/json", msg)
+}
+
+// chunks compresses msg and splits it into the datagrams of the GELF chunking
+// protocol if it does not fit into one.
+func (o *GELFOutput) chunks(msg []byte) ([][]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(msg)
+	_ = zw.Close()
+	data := buf.Bytes()
+	size := o.options.gelf.chunkSize
+	if len(data) <= size {
+		return [][]byte{data}, nil
+	}
+	size -= 12
+	count := (len(data) + size - 1) / size
+	if count > maxGELFChunks {
+		return nil, fmt.Errorf("GELF message of %d bytes exceeds %d chunks", len(data), maxGELFChunks)
+	}
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := make([]byte, 0, 12+end-i*size)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, data[i*size:end]...))
+	}
+	return chunks, nil
+}
+
+func (o *GELFOutput) message(e Entry) map[string]interface{} {
+	short, _, multiline := strings.Cut(e.Message, "\n")
+	if short == "" {
+		short = "-"
+	}
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          o.hostname,
+		"short_message": short,
+		"timestamp":     json.Number(strconv.FormatFloat(float64(e.Time.UnixMicro())/1e6, 'f', 6, 64)),
+		"level":         syslogSeverity(e.Level),
+		"_logger":       e.Logger,
+	}
+	full := e.Message
+	var fields []interface{}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		key, value := fmt.Sprint(e.Fields[i]), e.Fields[i+1]
+		flat := appendFlat(nil, key, plainValue(value))
+		if v, ok := value.(interface{ stackTrace() stackFrames }); ok && len(v.stackTrace()) > 0 {
+			var sb strings.Builder
+			v.stackTrace().MarshalLogArray(textStackEncoder{&sb})
+			full += "\n" + key + ":" + sb.String()
+			multiline = true
+			flat = withoutPrefix(flat, key+".stack.")
+		}
+		fields = append(fields, flat...)
+	}
+	if multiline {
+		msg["full_message"] = full
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if value := gelfValue(fields[i+1]); value != nil {
+			msg[gelfFieldName(fmt.Sprint(fields[i]))] = value
+		}
+	}
+	return msg
+}
+
+// withoutPrefix removes the fields with keys starting with prefix.
+func withoutPrefix(fields []interface{}, prefix string) []interface{} {
+	result := fields[:0]
+	for i := 0; i+1 < len(fields); i += 2 {
+		if !strings.HasPrefix(fmt.Sprint(fields[i]), prefix) {
+			result = append(result, fields[i], fields[i+1])
+		}
+	}
+	return result
+}
+
+var gelfInvalidChars = regexp.MustCompile(`[^\w.\-]`)
+
+// gelfFieldName returns key as the name of an additional field; _id is
+// reserved by Graylog.
+func gelfFieldName(key string) string {
+	key = gelfInvalidChars.ReplaceAllString(key, "_")
+	if key == "id" {
+		key = "id_"
+	}
+	return "_" + key
+}
+
+// gelfValue returns value as a number or a string, the only types GELF allows
+// for additional fields. NaN and the infinities, which JSON has no numbers
+// for, become strings.
+func gelfValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return stringValue(v)
+		}
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return stringValue(v)
+		}
+		return v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return v
+	}
+	return stringValue(value)
+}
+
+// endregion
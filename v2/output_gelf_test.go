@@ -0,0 +1,190 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFOutputUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	out, err := NewGELFOutput("udp", pc.LocalAddr().String(), GELFChunkSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("gelf", nil, WithOutput(out))
+	defer DeleteCustomLogger("gelf")
+	payload := make([]byte, 600)
+	for i := range payload {
+		payload[i] = byte('a' + i*7%26)
+	}
+	l.Error("failed\nsecond line", "id", 7, "http.path", "/x", "ok", true, "payload", string(payload))
+	out.Flush()
+
+	var data []byte
+	for count, seq := 0, 0; count == 0 || seq < count; seq++ {
+		buf := make([]byte, 200)
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > 100 || buf[0] != 0x1e || buf[1] != 0x0f || int(buf[10]) != seq {
+			t.Fatalf("unexpected chunk %d: % x", seq, buf[:12])
+		}
+		count = int(buf[11])
+		data = append(data, buf[12:n]...)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.NewDecoder(zr).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"version": "1.1", "short_message": "failed", "full_message": "failed\nsecond line", "level": 3.0,
+		"_logger": "gelf", "_id_": 7.0, "_http.path": "/x", "_ok": "true", "_payload": string(payload),
+	}
+	for key, value := range want {
+		if msg[key] != value {
+			t.Errorf("%s = %v, want %v", key, msg[key], value)
+		}
+	}
+	if _, ok := msg["timestamp"].(float64); !ok || msg["host"] == nil {
+		t.Errorf("unexpected message: %v", msg)
+	}
+}
+
+func TestGELFOutputTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	out, err := NewGELFOutput("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("gelf-tcp", nil, WithOutput(out))
+	defer DeleteCustomLogger("gelf-tcp")
+	l.Info("one")
+	l.Info("two")
+	out.Flush()
+	for _, want := range []string{`"short_message":"one"`, `"short_message":"two"`} {
+		select {
+		case msg := <-messages:
+			if !strings.Contains(msg, want) || !strings.HasSuffix(msg, "}\x00") {
+				t.Fatalf("unexpected message: %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message")
+		}
+	}
+}
+
+func TestGELFOutputHTTP(t *testing.T) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r.URL.Path + " " + string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	out, err := NewGELFOutput("http", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("gelf-http", nil, WithOutput(out))
+	defer DeleteCustomLogger("gelf-http")
+	l.SetLevel("debug").Debug("hello", "user", "bob")
+	out.Flush()
+	select {
+	case body := <-bodies:
+		if !strings.HasPrefix(body, "/gelf {") || !strings.Contains(body, `"_user":"bob"`) || !strings.Contains(body, `"level":7`) {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a request")
+	}
+}
+
+func TestGELFOutputSkipsBadEntries(t *testing.T) {
+	bodies := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		if strings.Contains(string(body), `"short_message":"rejected"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	defer SetErrorHandler(defaultErrorHandler)
+	reported := make(chan error, 3)
+	SetErrorHandler(func(err error) { reported <- err })
+	out, err := NewGELFOutput("http", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("gelf-bad", nil, WithOutput(out))
+	defer DeleteCustomLogger("gelf-bad")
+	l.Info("nan", "ratio", math.NaN())
+	l.Info("rejected")
+	l.Info("valid")
+	out.Flush()
+
+	for _, want := range []string{`"_ratio":"NaN"`, `"short_message":"rejected"`, `"short_message":"valid"`} {
+		select {
+		case body := <-bodies:
+			if !strings.Contains(body, want) {
+				t.Fatalf("unexpected body: %s", body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a request")
+		}
+	}
+	select {
+	case err := <-reported:
+		if !strings.Contains(err.Error(), `dropped entry "rejected"`) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reported error")
+	}
+}
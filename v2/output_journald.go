@@ -0,0 +1,203 @@
+//go:build linux
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// region - journald
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldOutput writes entries to journald with its native protocol.
+type JournaldOutput struct {
+	addr    *net.UnixAddr
+	conn    *net.UnixConn
+	batcher *batcher
+}
+
+// NewJournaldOutput returns an output writing to the journald socket, or to
+// /run/systemd/journal/socket if socket is empty. The message, PRIORITY,
+// SYSLOG_IDENTIFIER (the logger id) and CODE_FILE and CODE_LINE (or
+// CODE_FUNC) from the caller field are sent as journal fields, and so are the
+// fields of the entries, with names in upper case. Entries too large for a
+// datagram are passed in a memfd.
+func NewJournaldOutput(socket string, opts ...OutputOption) (*JournaldOutput, error) {
+	if socket == "" {
+		socket = defaultJournaldSocket
+	}
+	o := &JournaldOutput{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}}
+	o.batcher = newBatcher("journald", newOutputOptions(opts), o.send)
+	return o, nil
+}
+
+func (o *JournaldOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *JournaldOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *JournaldOutput) Close() error {
+	o.batcher.close()
+	if o.conn != nil {
+		return o.conn.Close()
+	}
+	return nil
+}
+
+func (o *JournaldOutput) send(batch []Entry) error {
+	if o.conn == nil {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		o.conn = conn
+	}
+	for i, e := range batch {
+		data := journalEntry(e)
+		_, _, err := o.conn.WriteMsgUnix(data, nil, o.addr)
+		if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+			err = o.sendFile(data)
+		}
+		if err != nil {
+			_ = o.conn.Close()
+			o.conn = nil
+			return partialError{retry: batch[i:], err: err}
+		}
+	}
+	return nil
+}
+
+// sendFile passes data in a sealed memfd, or in an unlinked temporary file
+// where memfds are not available.
+func (o *JournaldOutput) sendFile(data []byte) error {
+	f, err := journalFile(data)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, _, err = o.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), o.addr)
+	return err
+}
+
+func journalFile(data []byte) (*os.File, error) {
+	if fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING); err == nil {
+		f := os.NewFile(uintptr(fd), "journal-entry")
+		if _, err := f.Write(data); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+		if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+	f, err := os.CreateTemp("/dev/shm", "journal-entry-")
+	if err != nil {
+		f, err = os.CreateTemp("", "journal-entry-")
+		if err != nil {
+			return nil, err
+		}
+	}
+	_ = os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func journalEntry(e Entry) []byte {
+	var b bytes.Buffer
+	appendJournalField(&b, "MESSAGE", e.Message)
+	appendJournalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(e.Level)))
+	if e.Logger != "" {
+		appendJournalField(&b, "SYSLOG_IDENTIFIER", e.Logger)
+	}
+	fields := flattenFields(e.Fields)
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value := fmt.Sprint(fields[i]), stringValue(fields[i+1])
+		if key == "caller" {
+			if file, line, ok := splitCaller(value); ok {
+				appendJournalField(&b, "CODE_FILE", file)
+				appendJournalField(&b, "CODE_LINE", line)
+			} else {
+				appendJournalField(&b, "CODE_FUNC", value)
+			}
+			continue
+		}
+		name := journalFieldName(key)
+		if journalReservedFields[name] {
+			name = "F_" + name
+		}
+		appendJournalField(&b, name, value)
+	}
+	return b.Bytes()
+}
+
+// journalReservedFields are the fields journalEntry sets itself; user fields
+// with these names are prefixed with F_.
+var journalReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// appendJournalField appends a field in the native protocol: KEY=value, or
+// the length-prefixed binary form for values containing newlines.
+func appendJournalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.ContainsRune(value, '\n') {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalFieldName returns key as a journal field name: upper case letters,
+// digits and underscores, not starting with an underscore or a digit, at most
+// 64 characters.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// endregion
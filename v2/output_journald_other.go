@@ -0,0 +1,27 @@
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+)
+
+// region - journald
+
+// JournaldOutput writes entries to journald, which is only available on
+// Linux.
+type JournaldOutput struct{}
+
+func NewJournaldOutput(socket string, opts ...OutputOption) (*JournaldOutput, error) {
+	return nil, errors.New("journald is only available on Linux")
+}
+
+func (o *JournaldOutput) Write(e Entry) {}
+
+func (o *JournaldOutput) Flush() {}
+
+func (o *JournaldOutput) Close() error {
+	return nil
+}
+
+// endregion
@@ -0,0 +1,125 @@
+//go:build linux
+
+package logging
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJournaldOutput(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_ = conn.SetReadBuffer(1 << 20)
+
+	out, err := NewJournaldOutput(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("journald", nil, WithOutput(out), WithCallerFormat(CallerFull))
+	defer DeleteCustomLogger("journald")
+	l.Warning("disk low", "free.bytes", 12, "note", "a\nb")
+	out.Flush()
+
+	buf := make([]byte, 1<<16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := string(buf[:n])
+	for _, want := range []string{"MESSAGE=disk low\n", "PRIORITY=4\n", "SYSLOG_IDENTIFIER=journald\n",
+		"CODE_FILE=" + filepath.Join(mustGetwd(t), "output_journald_test.go") + "\n", "FREE_BYTES=12\n",
+		"NOTE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"} {
+		if !strings.Contains(data, want) {
+			t.Fatalf("missing %q in %q", want, data)
+		}
+	}
+	if !strings.Contains(data, "CODE_LINE=") {
+		t.Fatalf("missing CODE_LINE in %q", data)
+	}
+}
+
+func TestJournaldOutputLargeEntry(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	out, err := NewJournaldOutput(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("journald-large", nil, WithOutput(out))
+	defer DeleteCustomLogger("journald-large")
+	large := strings.Repeat("x", 4<<20)
+	l.Info("large", "payload", large)
+	out.Flush()
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("no control message: %v", err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("no file descriptor: %v", err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal-entry")
+	defer f.Close()
+	_, _ = f.Seek(0, io.SeekStart)
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("MESSAGE=large\n")) || !bytes.Contains(data, []byte("PAYLOAD="+large+"\n")) {
+		t.Fatalf("unexpected entry of %d bytes", len(data))
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for key, want := range map[string]string{"http.status": "HTTP_STATUS", "_secret": "SECRET", "1st": "F_1ST", "": "F_"} {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJournalEntryReservedFields(t *testing.T) {
+	e := Entry{Level: zerolog.InfoLevel, Logger: "api", Message: "m", Fields: []interface{}{
+		"priority", "high", "syslog_identifier", "x", "code_line", 1, "message", "user"}}
+	data := string(journalEntry(e))
+	want := "MESSAGE=m\nPRIORITY=6\nSYSLOG_IDENTIFIER=api\nF_PRIORITY=high\nF_SYSLOG_IDENTIFIER=x\nF_CODE_LINE=1\nF_MESSAGE=user\n"
+	if data != want {
+		t.Fatalf("unexpected entry: %q", data)
+	}
+}
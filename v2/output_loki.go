@@ -0,0 +1,227 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// region - loki
+
+const (
+	defaultLokiMaxLabelValues = 100
+	lokiOverflowValue         = "_overflow"
+)
+
+type lokiOptions struct {
+	labels         []string
+	maxLabelValues int
+}
+
+// LokiLabels sets the keys that become stream labels: "logger" (the logger
+// id), "level", "service" (the service.name resource attribute) or the key of
+// a field. The default is logger, level and service.
+func LokiLabels(keys ...string) OutputOption {
+	return func(o *outputOptions) {
+		o.loki.labels = keys
+	}
+}
+
+// LokiMaxLabelValues limits the number of distinct values of each label.
+// Further values are replaced with "_overflow" and the field is kept in the
+// line instead, so a field with unbounded values cannot create unbounded
+// streams.
+func LokiMaxLabelValues(n int) OutputOption {
+	return func(o *outputOptions) {
+		if n > 0 {
+			o.loki.maxLabelValues = n
+		}
+	}
+}
+
+// LokiOutput pushes entries to Grafana Loki.
+type LokiOutput struct {
+	endpoint string
+	options  outputOptions
+	service  string
+	values   map[string]map[string]bool
+	batcher  *batcher
+}
+
+// NewLokiOutput returns an output pushing to Loki at endpoint, e.g.
+// http://localhost:3100; /loki/api/v1/push is appended to endpoints without a
+// path. Each entry is a JSON line holding the message and the fields that are
+// not labels. Use OutputHeader to set the X-Scope-OrgID of a tenant and
+// OutputGzip to compress the pushes.
+func NewLokiOutput(endpoint string, opts ...OutputOption) (*LokiOutput, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Loki endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/loki/api/v1/push"
+	}
+	o := &LokiOutput{endpoint: u.String(), options: newOutputOptions(opts), values: make(map[string]map[string]bool)}
+	resource := o.options.resourceAttributes()
+	for i := 0; i+1 < len(resource); i += 2 {
+		if fmt.Sprint(resource[i]) == "service.name" {
+			o.service = stringValue(resource[i+1])
+		}
+	}
+	o.batcher = newBatcher("loki", o.options, o.send)
+	return o, nil
+}
+
+func (o *LokiOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *LokiOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *LokiOutput) Close() error {
+	o.batcher.close()
+	return nil
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (o *LokiOutput) send(batch []Entry) error {
+	body, err := json.Marshal(o.push(batch))
+	if err != nil {
+		return permanentError{err}
+	}
+	return o.options.post(o.endpoint, "application/json", body)
+}
+
+// push groups the batch into streams by label set.
+func (o *LokiOutput) push(batch []Entry) lokiPush {
+	var push lokiPush
+	index := make(map[string]int)
+	for _, e := range batch {
+		labels, line := o.labels(e)
+		key := lokiStreamKey(labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(push.Streams)
+			index[key] = i
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), line})
+	}
+	return push
+}
+
+// labels returns the labels of e and its line with the remaining fields.
+func (o *LokiOutput) labels(e Entry) (map[string]string, string) {
+	labels := make(map[string]string)
+	used := make(map[string]bool)
+	for _, key := range o.options.loki.labels {
+		var value string
+		switch key {
+		case "logger":
+			value = e.Logger
+		case "level":
+			value = e.Level.String()
+		case "service":
+			value = o.service
+		default:
+			v, ok := e.field(key)
+			if !ok {
+				continue
+			}
+			value = stringValue(plainValue(v))
+			if o.limit(key, value) {
+				value = lokiOverflowValue
+			} else {
+				used[key] = true
+			}
+		}
+		if value != "" {
+			labels[lokiLabelName(key)] = value
+		}
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	appendJSONField(&b, zerolog.MessageFieldName, e.Message)
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		key := fmt.Sprint(e.Fields[i])
+		if !used[key] {
+			b.WriteByte(',')
+			appendJSONField(&b, key, plainValue(e.Fields[i+1]))
+		}
+	}
+	b.WriteByte('}')
+	return labels, b.String()
+}
+
+// limit records value as a value of the label key and reports whether the
+// label has too many distinct values to take it.
+func (o *LokiOutput) limit(key, value string) bool {
+	values := o.values[key]
+	if values == nil {
+		values = make(map[string]bool)
+		o.values[key] = values
+	}
+	if values[value] {
+		return false
+	}
+	if len(values) >= o.options.loki.maxLabelValues {
+		return true
+	}
+	values[value] = true
+	if len(values) == o.options.loki.maxLabelValues {
+		reportError(fmt.Errorf("loki: label %s reached %d values, further values are replaced with %s", key, len(values), lokiOverflowValue))
+	}
+	return false
+}
+
+func appendJSONField(b *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(stringValue(value))
+	}
+	b.Write(k)
+	b.WriteByte(':')
+	b.Write(v)
+}
+
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key + "=" + strconv.Quote(labels[key]) + ",")
+	}
+	return sb.String()
+}
+
+// lokiLabelName returns key as a Prometheus label name.
+func lokiLabelName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// endregion
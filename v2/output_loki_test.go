@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestLokiOutput(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []lokiPush
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("X-Scope-OrgID") != "team" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var push lokiPush
+		if err := json.NewDecoder(zr).Decode(&push); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		pushes = append(pushes, push)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	out, err := NewLokiOutput(server.URL, OutputGzip(), OutputHeader("X-Scope-OrgID", "team"),
+		LokiLabels("logger", "level", "service", "tenant.id"), LokiMaxLabelValues(2), OutputResource("service.name", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("loki", nil, WithOutput(out))
+	defer DeleteCustomLogger("loki")
+	l.Info("first", "tenant.id", "a", "n", 1)
+	l.Info("second", "tenant.id", "a")
+	l.Warning("third", "tenant.id", "b")
+	l.Warning("fourth", "tenant.id", "c")
+	out.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 || len(pushes[0].Streams) != 3 {
+		t.Fatalf("unexpected pushes: %+v", pushes)
+	}
+	want := []struct {
+		labels map[string]string
+		lines  []string
+	}{
+		{map[string]string{"logger": "loki", "level": "info", "service": "api", "tenant_id": "a"}, []string{`{"message":"first","n":1}`, `{"message":"second"}`}},
+		{map[string]string{"logger": "loki", "level": "warn", "service": "api", "tenant_id": "b"}, []string{`{"message":"third"}`}},
+		{map[string]string{"logger": "loki", "level": "warn", "service": "api", "tenant_id": "_overflow"}, []string{`{"message":"fourth","tenant.id":"c"}`}},
+	}
+	for i, w := range want {
+		s := pushes[0].Streams[i]
+		if len(s.Stream) != len(w.labels) || len(s.Values) != len(w.lines) {
+			t.Fatalf("unexpected stream %d: %+v", i, s)
+		}
+		for key, value := range w.labels {
+			if s.Stream[key] != value {
+				t.Errorf("stream %d: label %s = %q, want %q", i, key, s.Stream[key], value)
+			}
+		}
+		for j, line := range w.lines {
+			if s.Values[j][1] != line || s.Values[j][0] == "" {
+				t.Errorf("stream %d: value %d = %v, want %s", i, j, s.Values[j], line)
+			}
+		}
+	}
+}
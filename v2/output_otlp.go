@@ -0,0 +1,311 @@
+package logging
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region - otlp
+
+// OTLPOutput exports entries as OTLP logs over HTTP with JSON encoding.
+type OTLPOutput struct {
+	endpoint string
+	options  outputOptions
+	resource []otlpKeyValue
+	batcher  *batcher
+}
+
+// NewOTLPOutput returns an output exporting to the OTLP/HTTP endpoint of a
+// collector, e.g. http://localhost:4318; /v1/logs is appended to endpoints
+// without a path. The trace_id, span_id and trace_flags fields bound by
+// WithContext become the trace correlation of the records.
+func NewOTLPOutput(endpoint string, opts ...OutputOption) (*OTLPOutput, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	o := &OTLPOutput{endpoint: u.String(), options: newOutputOptions(opts)}
+	o.resource = otlpAttributes(o.options.resourceAttributes())
+	o.batcher = newBatcher("otlp", o.options, o.send)
+	return o, nil
+}
+
+func (o *OTLPOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *OTLPOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *OTLPOutput) Close() error {
+	o.batcher.close()
+	return nil
+}
+
+func (o *OTLPOutput) send(batch []Entry) error {
+	body, err := json.Marshal(o.request(batch))
+	if err != nil {
+		return permanentError{err}
+	}
+	return o.options.post(o.endpoint, "application/json", body)
+}
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+type otlpScope struct {
+	Name string `json:"name"`
+}
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                int            `json:"flags,omitempty"`
+}
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+type otlpAnyValue struct {
+	StringValue *string        `json:"stringValue,omitempty"`
+	BoolValue   *bool          `json:"boolValue,omitempty"`
+	IntValue    *string        `json:"intValue,omitempty"`
+	DoubleValue *otlpDouble    `json:"doubleValue,omitempty"`
+	BytesValue  *string        `json:"bytesValue,omitempty"`
+	ArrayValue  *otlpArray     `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKeyValues `json:"kvlistValue,omitempty"`
+}
+
+// otlpDouble is a double encoded the way proto3 JSON does, with NaN and the
+// infinities as the strings "NaN", "Infinity" and "-Infinity", which
+// encoding/json rejects as numbers.
+type otlpDouble float64
+
+func (d otlpDouble) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return json.Marshal(f)
+}
+func (d *otlpDouble) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case `"NaN"`:
+		*d = otlpDouble(math.NaN())
+	case `"Infinity"`:
+		*d = otlpDouble(math.Inf(1))
+	case `"-Infinity"`:
+		*d = otlpDouble(math.Inf(-1))
+	default:
+		var f float64
+		if err := json.Unmarshal(b, &f); err != nil {
+			return err
+		}
+		*d = otlpDouble(f)
+	}
+	return nil
+}
+type otlpArray struct {
+	Values []otlpAnyValue `json:"values"`
+}
+type otlpKeyValues struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// request groups the batch by logger, the instrumentation scope of OTLP.
+func (o *OTLPOutput) request(batch []Entry) otlpRequest {
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+	var scopes []otlpScopeLogs
+	index := make(map[string]int)
+	for _, e := range batch {
+		i, ok := index[e.Logger]
+		if !ok {
+			i = len(scopes)
+			index[e.Logger] = i
+			scopes = append(scopes, otlpScopeLogs{Scope: otlpScope{Name: e.Logger}})
+		}
+		scopes[i].LogRecords = append(scopes[i].LogRecords, otlpRecord(e, observed))
+	}
+	return otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: o.resource},
+		ScopeLogs: scopes,
+	}}}
+}
+
+func otlpRecord(e Entry, observed string) otlpLogRecord {
+	message := e.Message
+	r := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(e.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: observed,
+		SeverityNumber:       otlpSeverity(e.Level),
+		SeverityText:         strings.ToUpper(e.Level.String()),
+		Body:                 otlpAnyValue{StringValue: &message},
+	}
+	var attributes []interface{}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		key, value := fmt.Sprint(e.Fields[i]), e.Fields[i+1]
+		s, _ := value.(string)
+		switch key {
+		case "trace_id":
+			if b, err := hex.DecodeString(s); err == nil && len(b) == 16 {
+				r.TraceID = s
+				continue
+			}
+		case "span_id":
+			if b, err := hex.DecodeString(s); err == nil && len(b) == 8 {
+				r.SpanID = s
+				continue
+			}
+		case "trace_flags":
+			if b, err := hex.DecodeString(s); err == nil && len(b) == 1 {
+				r.Flags = int(b[0])
+				continue
+			}
+		}
+		attributes = append(attributes, key, value)
+	}
+	r.Attributes = otlpAttributes(attributes)
+	return r
+}
+
+// otlpSeverity maps levels to the severity numbers of the OTLP log data
+// model; Panic is the most severe of the fatal range.
+func otlpSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel:
+		return 1
+	case zerolog.DebugLevel:
+		return 5
+	case zerolog.InfoLevel:
+		return 9
+	case zerolog.WarnLevel:
+		return 13
+	case zerolog.ErrorLevel:
+		return 17
+	case zerolog.FatalLevel:
+		return 21
+	case zerolog.PanicLevel:
+		return 24
+	}
+	return 0
+}
+
+func otlpAttributes(args []interface{}) []otlpKeyValue {
+	var result []otlpKeyValue
+	for i := 0; i+1 < len(args); i += 2 {
+		result = append(result, otlpKeyValue{Key: fmt.Sprint(args[i]), Value: otlpValue(plainValue(args[i+1]))})
+	}
+	return result
+}
+
+func otlpValue(value interface{}) otlpAnyValue {
+	switch v := value.(type) {
+	case nil:
+		return otlpAnyValue{}
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case []byte:
+		s := base64.StdEncoding.EncodeToString(v)
+		return otlpAnyValue{BytesValue: &s}
+	case time.Duration:
+		s := v.String()
+		return otlpAnyValue{StringValue: &s}
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		return otlpAnyValue{StringValue: &s}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			s := strconv.FormatInt(i, 10)
+			return otlpAnyValue{IntValue: &s}
+		}
+		f, _ := v.Float64()
+		d := otlpDouble(f)
+		return otlpAnyValue{DoubleValue: &d}
+	case map[string]interface{}:
+		kv := &otlpKeyValues{Values: []otlpKeyValue{}}
+		for _, key := range sortedKeys(v) {
+			kv.Values = append(kv.Values, otlpKeyValue{Key: key, Value: otlpValue(plainValue(v[key]))})
+		}
+		return otlpAnyValue{KvlistValue: kv}
+	case []interface{}:
+		arr := &otlpArray{Values: []otlpAnyValue{}}
+		for _, item := range v {
+			arr.Values = append(arr.Values, otlpValue(plainValue(item)))
+		}
+		return otlpAnyValue{ArrayValue: arr}
+	}
+	if s, ok := textValue(value); ok {
+		return otlpAnyValue{StringValue: &s}
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := strconv.FormatInt(rv.Int(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() <= math.MaxInt64 {
+			s := strconv.FormatInt(int64(rv.Uint()), 10)
+			return otlpAnyValue{IntValue: &s}
+		}
+		s := strconv.FormatUint(rv.Uint(), 10)
+		return otlpAnyValue{StringValue: &s}
+	case reflect.Float32, reflect.Float64:
+		d := otlpDouble(rv.Float())
+		return otlpAnyValue{DoubleValue: &d}
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return otlpValue(items)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			for _, key := range rv.MapKeys() {
+				m[key.String()] = rv.MapIndex(key).Interface()
+			}
+			return otlpValue(m)
+		}
+	}
+	s := fmt.Sprint(value)
+	return otlpAnyValue{StringValue: &s}
+}
+
+// endregion
@@ -0,0 +1,208 @@
+package logging
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPOutput(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpRequest
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Encoding") != "gzip" || r.Header.Get("Authorization") != "Bearer t" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(zr).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+	}))
+	defer server.Close()
+
+	out, err := NewOTLPOutput(server.URL, OutputGzip(), OutputHeader("Authorization", "Bearer t"),
+		OutputRetries(2, time.Millisecond), OutputResource("service.name", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("otlp", nil, WithOutput(out))
+	defer DeleteCustomLogger("otlp")
+	tc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	l.WithContext(WithTraceContext(context.Background(), tc)).Warning("disk low", "free", 12)
+	out.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(requests) != 1 {
+		t.Fatalf("unexpected requests: %d calls, %v", calls, requests)
+	}
+	rl := requests[0].ResourceLogs[0]
+	if a := rl.Resource.Attributes; len(a) != 2 || a[0].Key != "host.name" || a[1].Key != "service.name" || *a[1].Value.StringValue != "api" {
+		t.Fatalf("unexpected resource: %+v", a)
+	}
+	if rl.ScopeLogs[0].Scope.Name != "otlp" || len(rl.ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("unexpected scope logs: %+v", rl.ScopeLogs)
+	}
+	r := rl.ScopeLogs[0].LogRecords[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "WARN" || *r.Body.StringValue != "disk low" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" || r.Flags != 1 {
+		t.Fatalf("unexpected trace correlation: %+v", r)
+	}
+	if len(r.Attributes) != 1 || r.Attributes[0].Key != "free" || *r.Attributes[0].Value.IntValue != "12" {
+		t.Fatalf("unexpected attributes: %+v", r.Attributes)
+	}
+}
+
+func TestOTLPOutputNonFiniteFloats(t *testing.T) {
+	var mu sync.Mutex
+	var records []otlpLogRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	out, err := NewOTLPOutput(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("otlp-nan", nil, WithOutput(out))
+	defer DeleteCustomLogger("otlp-nan")
+	l.Info("nan", "ratio", math.NaN(), "limit", math.Inf(-1))
+	l.Info("normal", "ratio", 0.5)
+	out.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 2 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	a := records[0].Attributes
+	if len(a) != 2 || !math.IsNaN(float64(*a[0].Value.DoubleValue)) || !math.IsInf(float64(*a[1].Value.DoubleValue), -1) {
+		t.Fatalf("unexpected attributes: %+v", a)
+	}
+	if v := records[1].Attributes[0].Value.DoubleValue; v == nil || *v != 0.5 {
+		t.Fatalf("unexpected attributes: %+v", records[1].Attributes)
+	}
+}
+
+func TestOTLPOutputEndpoint(t *testing.T) {
+	if _, err := NewOTLPOutput("localhost:4318"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+type entryOutput struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (o *entryOutput) Write(e Entry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = append(o.entries, e)
+}
+func (o *entryOutput) Close() error {
+	return nil
+}
+
+func TestOutputFieldSnapshot(t *testing.T) {
+	out := &entryOutput{}
+	l := GetCustomLogger("output-snapshot", nil, WithOutput(out))
+	defer DeleteCustomLogger("output-snapshot")
+	m := map[string]int{"a": 1}
+	l.Info("snapshot", "m", m, "err", errors.New("failed"))
+	m["a"] = 2
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	fields := out.entries[0].Fields
+	if v, ok := fields[1].(map[string]interface{}); !ok || v["a"] != 1 {
+		t.Fatalf("unexpected map field: %#v", fields[1])
+	}
+	if v, ok := fields[3].(objectSnapshot); !ok || v.errorMessage != "failed" || v.errorType != "*errors.errorString" {
+		t.Fatalf("unexpected error field: %#v", fields[3])
+	}
+}
+
+type requestHook struct{}
+
+func (requestHook) Run(e *zerolog.Event, level zerolog.Level, message string) {
+	if message == "drop" {
+		e.Discard()
+		return
+	}
+	e.Str("request", "r1")
+}
+
+func TestOutputZerologHooks(t *testing.T) {
+	out := &entryOutput{}
+	l := GetLogger("output-hooks", WithOutput(out), WithHook(requestHook{}))
+	defer DeleteLogger("output-hooks")
+	l.Info("keep")
+	l.Info("drop")
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if len(out.entries) != 1 {
+		t.Fatalf("unexpected entries: %+v", out.entries)
+	}
+	if fields := out.entries[0].Fields; len(fields) != 2 || fields[0] != "request" || fields[1] != "r1" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+func TestBatcherCloseSkipsRetries(t *testing.T) {
+	defer SetErrorHandler(defaultErrorHandler)
+	SetErrorHandler(func(err error) {})
+	o := outputOptions{batchSize: 10, flushInterval: time.Hour, queueSize: 10, retries: 3, backoff: time.Minute}
+	sends := 0
+	b := newBatcher("test", o, func(batch []Entry) error {
+		sends++
+		return errors.New("unavailable")
+	})
+	b.add(Entry{Message: "m"})
+	start := time.Now()
+	b.close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second || sends != 1 {
+		t.Fatalf("close took %s with %d sends", elapsed, sends)
+	}
+}
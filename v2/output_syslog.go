@@ -0,0 +1,274 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// region - syslog
+
+// Facility is a syslog facility.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFtp
+)
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// syslogSDID is the SD-ID of the structured data element holding the fields,
+// under the private enterprise number reserved for documentation.
+const syslogSDID = "fields@32473"
+
+type syslogOptions struct {
+	facility Facility
+	appName  string
+	rfc3164  bool
+}
+
+// SyslogFacility sets the facility of the messages, user by default.
+func SyslogFacility(f Facility) OutputOption {
+	return func(o *outputOptions) {
+		if f >= FacilityKern && f <= FacilityLocal7 {
+			o.syslog.facility = f
+		}
+	}
+}
+
+// SyslogAppName sets the APP-NAME of the messages, the service name by
+// default.
+func SyslogAppName(name string) OutputOption {
+	return func(o *outputOptions) {
+		o.syslog.appName = name
+	}
+}
+
+// SyslogRFC3164 sends messages in the BSD format of RFC 3164 instead of RFC
+// 5424. The fields are appended to the message as key=value pairs.
+func SyslogRFC3164() OutputOption {
+	return func(o *outputOptions) {
+		o.syslog.rfc3164 = true
+	}
+}
+
+// SyslogOutput sends entries to a syslog server.
+type SyslogOutput struct {
+	network  string
+	options  outputOptions
+	hostname string
+	appName  string
+	procID   string
+	conn     *outputConn
+	stream   bool
+	batcher  *batcher
+}
+
+// NewSyslogOutput returns an output sending to a syslog server over the
+// network "udp", "tcp", "tls" (TCP with TLS) or "unix". Messages over TCP and
+// TLS are framed by octet counting (RFC 6587). An empty network and address
+// send to the local syslog daemon at /dev/log; addresses without a port get
+// 514, or 6514 for TLS. The MSGID is the logger id and the fields go into the
+// STRUCTURED-DATA.
+func NewSyslogOutput(network, address string, opts ...OutputOption) (*SyslogOutput, error) {
+	switch network {
+	case "":
+		network = "unix"
+	case "udp", "tcp", "tls", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if network == "unix" {
+		if address == "" {
+			address = "/dev/log"
+		}
+	} else if _, _, err := net.SplitHostPort(address); err != nil {
+		port := "514"
+		if network == "tls" {
+			port = "6514"
+		}
+		address = net.JoinHostPort(address, port)
+	}
+	o := &SyslogOutput{
+		network:  network,
+		options:  newOutputOptions(opts),
+		hostname: hostName(),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	o.appName = o.options.syslog.appName
+	if o.appName == "" {
+		o.appName = serviceName()
+	}
+	o.stream = network == "tcp" || network == "tls"
+	o.conn = &outputConn{dial: func() (net.Conn, error) {
+		if network != "unix" {
+			return dialOutput(network, address, o.options.tls)
+		}
+		conn, err := dialOutput("unixgram", address, nil)
+		if err != nil {
+			conn, err = dialOutput("unix", address, nil)
+			o.stream = err == nil
+		}
+		return conn, err
+	}}
+	o.batcher = newBatcher("syslog", o.options, o.send)
+	return o, nil
+}
+
+func (o *SyslogOutput) Write(e Entry) {
+	o.batcher.add(e)
+}
+
+// Flush sends the queued entries and waits until they are sent.
+func (o *SyslogOutput) Flush() {
+	o.batcher.flush()
+}
+
+func (o *SyslogOutput) Close() error {
+	o.batcher.close()
+	return o.conn.close()
+}
+
+func (o *SyslogOutput) send(batch []Entry) error {
+	for i, e := range batch {
+		if err := o.conn.write(o.frame(o.message(e))); err != nil {
+			return partialError{retry: batch[i:], err: err}
+		}
+	}
+	return nil
+}
+
+// frame frames msg for the transport: octet counting for RFC 5424 over TCP,
+// a trailing newline for other streams, nothing for datagrams.
+func (o *SyslogOutput) frame(msg string) []byte {
+	switch {
+	case o.stream && !o.options.syslog.rfc3164 && o.network != "unix":
+		return []byte(strconv.Itoa(len(msg)) + " " + msg)
+	case o.stream:
+		return []byte(msg + "\n")
+	}
+	return []byte(msg)
+}
+
+func (o *SyslogOutput) message(e Entry) string {
+	pri := int(o.options.syslog.facility)*8 + syslogSeverity(e.Level)
+	fields := flattenFields(e.Fields)
+	var sb strings.Builder
+	if o.options.syslog.rfc3164 {
+		_, _ = fmt.Fprintf(&sb, "<%d>%s %s %s[%s]: %s", pri, e.Time.Format("Jan _2 15:04:05"),
+			syslogHeader(o.hostname, 255), syslogTag(o.appName), o.procID, e.Message)
+		for i := 0; i+1 < len(fields); i += 2 {
+			_, _ = fmt.Fprintf(&sb, " %s=%s", fields[i], stringValue(fields[i+1]))
+		}
+		return sb.String()
+	}
+	_, _ = fmt.Fprintf(&sb, "<%d>1 %s %s %s %s %s ", pri, e.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeader(o.hostname, 255), syslogHeader(o.appName, 48), o.procID, syslogHeader(e.Logger, 32))
+	if len(fields) == 0 {
+		sb.WriteString("-")
+	} else {
+		sb.WriteString("[" + syslogSDID)
+		for i := 0; i+1 < len(fields); i += 2 {
+			sb.WriteString(" " + syslogParamName(fmt.Sprint(fields[i])) + `="`)
+			sb.WriteString(syslogParamValue.Replace(stringValue(fields[i+1])))
+			sb.WriteString(`"`)
+		}
+		sb.WriteString("]")
+	}
+	if e.Message != "" {
+		sb.WriteString(" " + e.Message)
+	}
+	return sb.String()
+}
+
+// syslogSeverity maps levels to syslog severities; Trace shares debug.
+func syslogSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return 7
+	case zerolog.InfoLevel:
+		return 6
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.PanicLevel:
+		return 1
+	}
+	return 5
+}
+
+// syslogHeader returns s as a header field of at most n printable ASCII
+// characters, "-" (the nil value) if s is empty.
+func syslogHeader(s string, n int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > n {
+		s = s[:n]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func syslogTag(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+func syslogParamName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+var syslogParamValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// endregion
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogOutputTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			messages <- string(buf)
+		}
+	}()
+
+	out, err := NewSyslogOutput("tcp", ln.Addr().String(), SyslogFacility(FacilityLocal3), SyslogAppName("api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("syslog", nil, WithOutput(out))
+	defer DeleteCustomLogger("syslog")
+	l.Error("failed", "user", `a "b"]`, "n", 1)
+	l.Info("done")
+	out.Flush()
+
+	wants := []*regexp.Regexp{
+		regexp.MustCompile(`^<155>1 \d{4}-\d\d-\d\dT[0-9:.]+\S+ \S+ api \d+ syslog \[fields@32473 user="a \\"b\\"\\]" n="1"\] failed$`),
+		regexp.MustCompile(`^<158>1 \S+ \S+ api \d+ syslog - done$`),
+	}
+	for _, want := range wants {
+		select {
+		case msg := <-messages:
+			if !want.MatchString(msg) {
+				t.Fatalf("unexpected message: %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message")
+		}
+	}
+}
+
+func TestSyslogOutputRFC3164(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	out, err := NewSyslogOutput("udp", pc.LocalAddr().String(), SyslogRFC3164(), SyslogAppName("my app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	l := GetCustomLogger("syslog-bsd", nil, WithOutput(out))
+	defer DeleteCustomLogger("syslog-bsd")
+	l.Warning("slow", "ms", 250)
+	out.Flush()
+
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := regexp.MustCompile(`^<12>[A-Z][a-z]{2} [ 0-9]\d \d\d:\d\d:\d\d \S+ my_app\[\d+\]: slow ms=250$`)
+	if msg := string(buf[:n]); !want.MatchString(msg) {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+}
+
+func TestSyslogOutputNetwork(t *testing.T) {
+	if _, err := NewSyslogOutput("sctp", "localhost"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"runtime"
+	"strings"
+)
+
+// region - panic recovery
+
+// PanicAction is what Recover does once the panic has been logged.
+type PanicAction int
+
+const (
+	// PanicRepanic re-panics with the recovered value.
+	PanicRepanic PanicAction = iota
+	// PanicExit runs the exit hooks, calls Shutdown and exits the process.
+	PanicExit
+	// PanicSwallow lets the goroutine continue as if nothing happened.
+	PanicSwallow
+)
+
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	action   PanicAction
+	level    zerolog.Level
+	exitCode int
+	fields   []interface{}
+}
+
+// RecoverAction sets what happens after the panic is logged; the default is
+// PanicRepanic.
+func RecoverAction(action PanicAction) RecoverOption {
+	return func(o *recoverOptions) {
+		o.action = action
+	}
+}
+
+// RecoverExitCode sets the exit code used with PanicExit; the default is 2,
+// the same code the Go runtime uses for unrecovered panics.
+func RecoverExitCode(code int) RecoverOption {
+	return func(o *recoverOptions) {
+		o.exitCode = code
+	}
+}
+
+// RecoverLevel sets the level the panic is logged at: zerolog.PanicLevel
+// (default) or zerolog.ErrorLevel.
+func RecoverLevel(level zerolog.Level) RecoverOption {
+	return func(o *recoverOptions) {
+		o.level = level
+	}
+}
+
+// RecoverFields adds key/value pairs to the entry logged for the panic.
+func RecoverFields(args ...interface{}) RecoverOption {
+	return func(o *recoverOptions) {
+		o.fields = append(o.fields, args...)
+	}
+}
+
+// Recover logs a panic of the current goroutine through l. It must be called
+// directly by a deferred call:
+//
+//	defer logging.Recover(l)
+func Recover(l Logger, opts ...RecoverOption) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	o := recoverOptions{
+		action:   PanicRepanic,
+		level:    zerolog.PanicLevel,
+		exitCode: 2,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	logPanic(l, o, value)
+	switch o.action {
+	case PanicRepanic:
+		panic(value)
+	case PanicExit:
+		exit(o.exitCode)
+	}
+}
+
+// Go runs fn in a new goroutine, recovering and logging its panics the way
+// Recover does.
+func Go(l Logger, fn func(), opts ...RecoverOption) {
+	go func() {
+		defer Recover(l, opts...)
+		fn()
+	}()
+}
+
+func logPanic(l Logger, o recoverOptions, value interface{}) {
+	if l == nil {
+		return
+	}
+	args := append([]interface{}{Field{Key: "panic", Value: panicValue{value: value, stack: panicStack()}}}, o.fields...)
+	if o.level != zerolog.PanicLevel {
+		l.Error("recovered from panic", args...)
+		return
+	}
+	// Panic-level logging panics on some backends; that second panic must
+	// not escape.
+	defer func() {
+		_ = recover()
+	}()
+	l.Panic("recovered from panic", args...)
+}
+
+type panicValue struct {
+	value interface{}
+	stack stackFrames
+}
+
+func (v panicValue) MarshalLogObject(enc ObjectEncoder) {
+	enc.Add("value", normalizeValue(v.value))
+	enc.Add("type", fmt.Sprintf("%T", v.value))
+	enc.Add("stack", v.stack)
+}
+
+// panicStack returns the stack of the panicking goroutine, starting at the
+// function that panicked.
+func panicStack() stackFrames {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	start := 0
+	for i := 0; i < n; i++ {
+		fn := runtime.FuncForPC(pcs[i] - 1)
+		if fn == nil {
+			continue
+		}
+		if fn.Name() == "runtime.gopanic" {
+			start = i + 1
+		} else if start > 0 && !strings.HasPrefix(fn.Name(), "runtime.") {
+			break
+		} else if start > 0 {
+			start = i + 1
+		}
+	}
+	return pcs[start:n]
+}
+
+func (v panicValue) stackTrace() stackFrames {
+	return v.stack
+}
+
+// endregion
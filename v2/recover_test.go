@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"errors"
+	"github.com/rs/zerolog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func panicking() {
+	panic(errors.New("boom"))
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	var out string
+	l := GetCustomLogger("recover-repanic", func(msg string) { out = msg })
+	defer DeleteCustomLogger("recover-repanic")
+	defer func() {
+		if v := recover(); v == nil || v.(error).Error() != "boom" {
+			t.Fatalf("expected re-panic with the original value, got %v", v)
+		}
+		if !strings.Contains(out, "PNC [recover-repanic] recovered from panic  panic=boom panic.type=*errors.errorString request=42") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+		if !strings.Contains(out, "\n\tat go.slink.ws/logging/v2.panicking (") {
+			t.Fatalf("expected the stack to start at the panicking function: %q", out)
+		}
+	}()
+	func() {
+		defer Recover(l, RecoverFields("request", 42))
+		panicking()
+	}()
+}
+
+func TestGoSwallow(t *testing.T) {
+	messages := make(chan string, 1)
+	l := GetCustomLogger("recover-go", func(msg string) { messages <- msg })
+	defer DeleteCustomLogger("recover-go")
+	Go(l, func() {
+		panic("background")
+	}, RecoverAction(PanicSwallow), RecoverLevel(zerolog.ErrorLevel))
+	select {
+	case out := <-messages:
+		if !strings.Contains(out, "ERR [recover-go] recovered from panic  panic=background panic.type=string") {
+			t.Fatalf("unexpected output: %q", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the panic was not logged")
+	}
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// region - request id
+
+const maxRequestIDLength = 128
+
+// RequestIDOption configures RequestIDMiddleware and RequestIDRoundTripper.
+type RequestIDOption func(*requestIDOptions)
+
+type requestIDOptions struct {
+	header   string
+	generate func() string
+}
+
+// RequestIDHeaderName replaces X-Request-ID as the header carrying the id.
+func RequestIDHeaderName(name string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		if name != "" {
+			o.header = name
+		}
+	}
+}
+
+// RequestIDGenerator replaces NewRequestID as the generator of ids.
+func RequestIDGenerator(fn func() string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		if fn != nil {
+			o.generate = fn
+		}
+	}
+}
+
+func newRequestIDOptions(opts []RequestIDOption) requestIDOptions {
+	o := requestIDOptions{header: RequestIDHeader, generate: NewRequestID}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// RequestIDMiddleware takes the request id from the X-Request-ID header, or
+// generates one if the header is missing or invalid, stores it in the
+// context of the request with WithRequestID and echoes it in the response.
+// Loggers derived with WithContext add it to every entry as request_id.
+func RequestIDMiddleware(opts ...RequestIDOption) func(http.Handler) http.Handler {
+	o := newRequestIDOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(o.header)
+			if !validRequestID(id) {
+				id = o.generate()
+			}
+			w.Header().Set(o.header, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// RequestIDRoundTripper sends the request id of the context of every request
+// downstream in the X-Request-ID header.
+func RequestIDRoundTripper(next http.RoundTripper, opts ...RequestIDOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	o := newRequestIDOptions(opts)
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if id, ok := RequestIDFromContext(r.Context()); ok && r.Header.Get(o.header) == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set(o.header, id)
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// NewRequestID returns a UUIDv7: 48 bits of Unix milliseconds followed by
+// random bits, so ids sort by creation time.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// validRequestID accepts ids from clients only if they are short and made of
+// printable ASCII without spaces, as they end up in every entry.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// endregion
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if !uuidV7.MatchString(a) || a == b {
+		t.Fatalf("unexpected ids: %s %s", a, b)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var out []string
+	var downstream string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get("X-Correlation-ID")
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: RequestIDRoundTripper(nil, RequestIDHeaderName("X-Correlation-ID"))}
+
+	l := GetCustomLogger("request-id", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("request-id")
+	handler := RequestIDMiddleware(RequestIDHeaderName("X-Correlation-ID"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.WithContext(r.Context()).Info("handling")
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Correlation-ID", "abc-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("X-Correlation-ID") != "abc-1" || downstream != "abc-1" {
+		t.Fatalf("expected the id to be echoed and forwarded: %q %q", w.Header().Get("X-Correlation-ID"), downstream)
+	}
+	if len(out) != 1 || !strings.HasSuffix(out[0], "INF [request-id] handling  request_id=abc-1") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Correlation-ID", "bad id")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if id := w.Header().Get("X-Correlation-ID"); !uuidV7.MatchString(id) || downstream != id {
+		t.Fatalf("expected a generated id: %q %q", id, downstream)
+	}
+}
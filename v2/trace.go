@@ -0,0 +1,253 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// region - trace context
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+const maxTracestateLength = 512
+
+// TraceID is the id of a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID is the id of a span.
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// TraceContext holds the W3C trace context of a request.
+type TraceContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	State   string
+}
+
+// IsValid reports whether both ids are set.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != TraceID{} && tc.SpanID != SpanID{}
+}
+
+// IsSampled reports whether the sampled flag is set.
+func (tc TraceContext) IsSampled() bool {
+	return tc.Flags&0x01 != 0
+}
+
+// Traceparent returns the traceparent header value of tc.
+func (tc TraceContext) Traceparent() string {
+	var b [55]byte
+	copy(b[:], "00-")
+	hex.Encode(b[3:35], tc.TraceID[:])
+	b[35] = '-'
+	hex.Encode(b[36:52], tc.SpanID[:])
+	b[52] = '-'
+	hex.Encode(b[53:], []byte{tc.Flags})
+	return string(b[:])
+}
+
+// NewSpan returns a trace context for a child span: same trace, flags and
+// state, new span id.
+func (tc TraceContext) NewSpan() TraceContext {
+	tc.SpanID = newSpanID()
+	return tc
+}
+
+// NewTraceContext starts a new sampled trace.
+func NewTraceContext() TraceContext {
+	var tc TraceContext
+	for tc.TraceID == (TraceID{}) {
+		_, _ = rand.Read(tc.TraceID[:])
+	}
+	tc.SpanID = newSpanID()
+	tc.Flags = 0x01
+	return tc
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// ParseTraceparent parses a traceparent header value. Versions above 00 are
+// accepted as long as they start with the fields of version 00.
+func ParseTraceparent(s string) (TraceContext, error) {
+	var tc TraceContext
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tc, errors.New("malformed traceparent")
+	}
+	version, err := decodeLowerHex(s[0:2])
+	if err != nil || version[0] == 0xff {
+		return tc, errors.New("invalid traceparent version")
+	}
+	if version[0] == 0 && len(s) != 55 || len(s) > 55 && s[55] != '-' {
+		return tc, errors.New("malformed traceparent")
+	}
+	traceID, err := decodeLowerHex(s[3:35])
+	if err != nil {
+		return tc, errors.New("invalid trace id")
+	}
+	spanID, err := decodeLowerHex(s[36:52])
+	if err != nil {
+		return tc, errors.New("invalid span id")
+	}
+	flags, err := decodeLowerHex(s[53:55])
+	if err != nil {
+		return tc, errors.New("invalid trace flags")
+	}
+	copy(tc.TraceID[:], traceID)
+	copy(tc.SpanID[:], spanID)
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return TraceContext{}, errors.New("zero trace or span id")
+	}
+	return tc, nil
+}
+
+func decodeLowerHex(s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return nil, errors.New("invalid hex")
+		}
+	}
+	return hex.DecodeString(s)
+}
+
+// ParseTraceHeaders returns the trace context of the traceparent and
+// tracestate headers of h. An oversized tracestate is dropped.
+func ParseTraceHeaders(h http.Header) (TraceContext, error) {
+	tc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return tc, err
+	}
+	state := strings.TrimSpace(strings.Join(h.Values(TracestateHeader), ","))
+	if len(state) <= maxTracestateLength {
+		tc.State = state
+	}
+	return tc, nil
+}
+
+// SetTraceHeaders sets the traceparent and tracestate headers of h.
+func SetTraceHeaders(h http.Header, tc TraceContext) {
+	h.Set(TraceparentHeader, tc.Traceparent())
+	if tc.State != "" {
+		h.Set(TracestateHeader, tc.State)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+type traceContextKey struct{}
+
+// WithTraceContext returns a context carrying tc.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context of ctx, taken from the
+// extractor set with SetTraceExtractor first and from WithTraceContext
+// otherwise.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	if e := traceExtractor.Load(); e != nil {
+		if tc, ok := (*e).Extract(ctx); ok && tc.IsValid() {
+			return tc, true
+		}
+	}
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// TraceExtractor supplies the trace context of a context, e.g. from the span
+// of a tracing library.
+type TraceExtractor interface {
+	Extract(ctx context.Context) (TraceContext, bool)
+}
+
+type TraceExtractorFunc func(ctx context.Context) (TraceContext, bool)
+
+func (fn TraceExtractorFunc) Extract(ctx context.Context) (TraceContext, bool) {
+	return fn(ctx)
+}
+
+var traceExtractor atomic.Pointer[TraceExtractor]
+
+// SetTraceExtractor installs e as the first source of trace contexts; nil
+// removes it.
+func SetTraceExtractor(e TraceExtractor) {
+	if e == nil {
+		traceExtractor.Store(nil)
+		return
+	}
+	traceExtractor.Store(&e)
+}
+
+// traceFields returns the fields WithContext binds for the trace context.
+func traceFields(ctx context.Context) []interface{} {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []interface{}{
+		"trace_id", tc.TraceID.String(),
+		"span_id", tc.SpanID.String(),
+		"trace_flags", hex.EncodeToString([]byte{tc.Flags}),
+	}
+}
+
+// TraceMiddleware continues the trace of the traceparent header of every
+// request with a new span, or starts a new trace if the header is missing or
+// invalid, and stores the trace context in the context of the request.
+func TraceMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc, err := ParseTraceHeaders(r.Header)
+			if err != nil {
+				tc = NewTraceContext()
+			} else {
+				tc = tc.NewSpan()
+			}
+			next.ServeHTTP(w, r.WithContext(WithTraceContext(r.Context(), tc)))
+		})
+	}
+}
+
+// TraceRoundTripper sends the trace context of every request downstream in
+// the traceparent and tracestate headers, with a new span id per request.
+func TraceRoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if tc, ok := TraceContextFromContext(r.Context()); ok && r.Header.Get(TraceparentHeader) == "" {
+			r = r.Clone(r.Context())
+			SetTraceHeaders(r.Header, tc.NewSpan())
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+// endregion
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent(testTraceparent)
+	if err != nil || tc.Traceparent() != testTraceparent || !tc.IsSampled() {
+		t.Fatalf("unexpected result: %v %v", tc, err)
+	}
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Fatalf("expected a future version to be accepted: %v", err)
+	}
+	for _, s := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(s); err == nil {
+			t.Fatalf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	var out []string
+	var downstream http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: TraceRoundTripper(nil)}
+
+	l := GetCustomLogger("trace", func(msg string) { out = append(out, msg) })
+	defer DeleteCustomLogger("trace")
+	handler := TraceMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.WithContext(r.Context()).Info("handling")
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(TraceparentHeader, testTraceparent)
+	r.Header.Set(TracestateHeader, "vendor=value")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(out) != 1 || !strings.Contains(out[0], "handling  trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=") ||
+		!strings.HasSuffix(out[0], " trace_flags=01") || strings.Contains(out[0], "00f067aa0ba902b7") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	tc, err := ParseTraceHeaders(downstream)
+	if err != nil || tc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.State != "vendor=value" {
+		t.Fatalf("unexpected downstream trace context: %v %v", tc, err)
+	}
+}
+
+func TestTraceExtractor(t *testing.T) {
+	tc, _ := ParseTraceparent(testTraceparent)
+	SetTraceExtractor(TraceExtractorFunc(func(ctx context.Context) (TraceContext, bool) {
+		return tc, true
+	}))
+	defer SetTraceExtractor(nil)
+	var out string
+	defer DeleteCustomLogger("trace-extractor")
+	GetCustomLogger("trace-extractor", func(msg string) { out = msg }).WithContext(context.Background()).Info("hello")
+	if !strings.HasSuffix(out, "hello  trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
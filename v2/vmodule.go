@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// region - vmodule
+
+// vmoduleRule lowers the level of the call sites matching pattern.
+type vmoduleRule struct {
+	pattern string
+	level   zerolog.Level
+}
+
+type vmoduleState struct {
+	rules []vmoduleRule
+	// cache holds the level override of every call site seen so far, keyed
+	// by program counter; zerolog.Disabled means no rule matches
+	cache sync.Map
+}
+
+var vmodule atomic.Pointer[vmoduleState]
+
+func init() {
+	if err := SetVModule(os.Getenv("LOGGING_VMODULE")); err != nil {
+		reportError(fmt.Errorf("LOGGING_VMODULE: %w", err))
+	}
+}
+
+// SetVModule sets per-call-site level overrides, like glog's -vmodule, from a
+// comma separated list of pattern=level pairs, e.g. "pool.go=trace,cache/*=debug".
+// A pattern is matched against the trailing path elements of the caller's
+// file (pool.go, cache/*, internal/cache/*.go) or against the import path of
+// its package (go.slink.ws/app/cache). The first matching rule wins, and an
+// override only ever raises the verbosity of a logger. An empty spec removes
+// all overrides.
+func SetVModule(spec string) error {
+	var rules []vmoduleRule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, value, ok := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return fmt.Errorf("invalid vmodule rule %q", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid vmodule pattern %q: %w", pattern, err)
+		}
+		level, err := stringToLevel(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid vmodule level %q", value)
+		}
+		rules = append(rules, vmoduleRule{pattern: pattern, level: level})
+	}
+	if len(rules) == 0 {
+		vmodule.Store(nil)
+		return nil
+	}
+	vmodule.Store(&vmoduleState{rules: rules})
+	return nil
+}
+
+// vmoduleEnabled reports whether a rule enables level for the call site skip
+// frames above the function calling vmoduleEnabled. Without rules it costs a
+// single atomic load.
+func vmoduleEnabled(level zerolog.Level, skip int) bool {
+	s := vmodule.Load()
+	if s == nil {
+		return false
+	}
+	pc := callerPC(skip + 1)
+	if pc == 0 {
+		return false
+	}
+	if v, ok := s.cache.Load(pc); ok {
+		return level >= v.(zerolog.Level)
+	}
+	override := s.match(pc)
+	s.cache.Store(pc, override)
+	return level >= override
+}
+
+func (s *vmoduleState) match(pc uintptr) zerolog.Level {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := functionPackage(frame.Function)
+	file := frame.File
+	for _, rule := range s.rules {
+		if ok, _ := path.Match(rule.pattern, pkg); ok {
+			return rule.level
+		}
+		n := strings.Count(rule.pattern, "/") + 1
+		if ok, _ := path.Match(rule.pattern, lastPathElements(file, n)); ok {
+			return rule.level
+		}
+	}
+	return zerolog.Disabled
+}
+
+// functionPackage returns the import path of the package of a fully qualified
+// function name such as go.slink.ws/app/cache.(*Pool).Get.
+func functionPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/') + 1
+	if dot := strings.IndexByte(function[slash:], '.'); dot >= 0 {
+		return function[:slash+dot]
+	}
+	return function
+}
+
+func lastPathElements(file string, n int) string {
+	i := len(file)
+	for ; n > 0 && i > 0; n-- {
+		i = strings.LastIndexByte(file[:i], '/')
+		if i < 0 {
+			return file
+		}
+	}
+	return file[i+1:]
+}
+
+// endregion
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"testing"
+)
+
+func TestVModule(t *testing.T) {
+	defer SetVModule("")
+	var out string
+	l := GetCustomLogger("vmodule", func(msg string) { out = msg }).SetLevel("info")
+	defer DeleteCustomLogger("vmodule")
+
+	for _, spec := range []string{"vmodule_test.go=debug", "v2/vmodule_*.go=debug", "go.slink.ws/logging/*=debug"} {
+		if err := SetVModule(spec); err != nil {
+			t.Fatal(err)
+		}
+		out = ""
+		l.Debug("hello")
+		if out == "" || !l.IsDebugEnabled() {
+			t.Fatalf("%s: expected debug to be enabled", spec)
+		}
+		out = ""
+		l.Trace("hello")
+		if out != "" {
+			t.Fatalf("%s: expected trace to stay disabled: %q", spec, out)
+		}
+	}
+
+	if err := SetVModule("pool.go=trace"); err != nil {
+		t.Fatal(err)
+	}
+	out = ""
+	l.Debug("hello")
+	if out != "" {
+		t.Fatalf("expected debug to stay disabled: %q", out)
+	}
+}
+
+func TestSetVModuleInvalid(t *testing.T) {
+	defer SetVModule("")
+	for _, spec := range []string{"pool.go", "pool.go=loud", "[=debug"} {
+		if err := SetVModule(spec); err == nil {
+			t.Fatalf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestFunctionPackage(t *testing.T) {
+	for in, want := range map[string]string{
+		"go.slink.ws/app/cache.(*Pool).Get": "go.slink.ws/app/cache",
+		"main.main":                         "main",
+		"go.slink.ws/app/cache.init.func1":  "go.slink.ws/app/cache",
+	} {
+		if got := functionPackage(in); got != want {
+			t.Fatalf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"fmt"
+)
+
+// PyroscopeLoggerImpl adapts a Logger to the printf-style logger interface of
+// the Pyroscope client. Callers are reported at the Pyroscope call site.
+type PyroscopeLoggerImpl struct {
+	logger Logger
+}
+
+func (l *PyroscopeLoggerImpl) Infof(format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+func (l *PyroscopeLoggerImpl) Debugf(format string, args ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, args...))
+}
+func (l *PyroscopeLoggerImpl) Errorf(format string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+}
+
+func PyroscopeLogger(logger Logger) *PyroscopeLoggerImpl {
+	return &PyroscopeLoggerImpl{
+		logger: AddCallerSkip(logger, 1),
+	}
+}